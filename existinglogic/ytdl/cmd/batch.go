@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+)
+
+// batchLine is one link of a --batch-file with the options it overrides.
+type batchLine struct {
+	Number int
+	Link   string
+	// Overrides are applied in the order they are written, the last one of a key wins.
+	Overrides []override
+}
+
+// override is a key=value option of a batch file line.
+type override struct {
+	Key   string
+	Value string
+}
+
+// readBatchFile reads path, or stdin when path is "-".
+func readBatchFile(path string) ([]batchLine, error) {
+	if path == "-" {
+		return parseBatch(os.Stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseBatch(file)
+}
+
+// parseBatch reads one link per line, optionally followed by key=value overrides.
+// Values with spaces are quoted with " or ':
+//
+//	# comments and blank lines are ignored
+//	https://youtu.be/<id> dst=music format=audio
+//	https://youtu.be/<id> template="{index} - {title}" # trailing comment
+//
+// Every line is checked, a mistake on any line fails the whole file.
+func parseBatch(r io.Reader) ([]batchLine, error) {
+	var lines []batchLine
+
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields, err := splitBatchLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("batch file line %d: %w", number, err)
+		}
+		line := batchLine{Number: number, Link: fields[0]}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("batch file line %d: expected key=value, got %q", number, field)
+			}
+			line.Overrides = append(line.Overrides, override{key, value})
+		}
+		// unknown keys and bad values are reported before anything is downloaded
+		if _, err := line.apply(ytdl.DownloadOptions{}); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// splitBatchLine splits text on spaces, quoted parts of a field keep theirs.
+// Everything after an unquoted # starting a field is a trailing comment.
+func splitBatchLine(text string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	var quote rune
+	for _, c := range text {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote, inField = c, true
+		case unicode.IsSpace(c):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		case c == '#' && !inField:
+			return fields, nil
+		default:
+			field.WriteRune(c)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("missing closing %c", quote)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// apply returns opts with the overrides of the line applied.
+// Relative dst values are resolved against the --dst directory.
+func (line batchLine) apply(opts ytdl.DownloadOptions) (ytdl.DownloadOptions, error) {
+	for _, o := range line.Overrides {
+		var err error
+		value := o.Value
+		switch o.Key {
+		case "dst":
+			if !filepath.IsAbs(value) {
+				value = filepath.Join(opts.OutputDir, value)
+			}
+			opts.OutputDir = value
+		case "format":
+			// "audio" is a shortcut for --audio-only
+			if value == "audio" {
+				opts.AudioOnly = true
+				opts.Format = ""
+			} else {
+				opts.Format = value
+			}
+		case "template":
+			opts.Template = value
+		case "audio-only":
+			opts.AudioOnly, err = strconv.ParseBool(value)
+		case "video-only":
+			opts.VideoOnly, err = strconv.ParseBool(value)
+		case "author":
+			opts.IncludeAuthor, err = strconv.ParseBool(value)
+		default:
+			return opts, fmt.Errorf("batch file line %d: unknown option %q, expected dst, format, template, audio-only, video-only or author", line.Number, o.Key)
+		}
+		if err != nil {
+			return opts, fmt.Errorf("batch file line %d: %s: %w", line.Number, o.Key, err)
+		}
+	}
+
+	return opts, nil
+}
+
+// batchLinks appends the links of lines to links for the mp3 command,
+// which downloads them all the same way: lines can't override options.
+func batchLinks(lines []batchLine, links []string) ([]string, error) {
+	for _, line := range lines {
+		if len(line.Overrides) > 0 {
+			return nil, fmt.Errorf("batch file line %d: options like %s=%s need the download command", line.Number, line.Overrides[0].Key, line.Overrides[0].Value)
+		}
+		links = append(links, line.Link)
+	}
+	return links, nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+)
+
+func TestParseBatch(t *testing.T) {
+	text := `# downloads for the weekend
+
+https://youtu.be/aaaaaaaaaaa
+  https://youtu.be/bbbbbbbbbbb dst=music format=audio # trailing comment
+https://youtu.be/ccccccccccc template="{index} - {title}" dst='My Talks'
+https://youtu.be/ddddddddddd#t=30 author=true
+`
+	lines, err := parseBatch(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		number    int
+		link      string
+		overrides string
+	}{
+		{3, "https://youtu.be/aaaaaaaaaaa", ""},
+		{4, "https://youtu.be/bbbbbbbbbbb", "dst=music|format=audio"},
+		{5, "https://youtu.be/ccccccccccc", "template={index} - {title}|dst=My Talks"},
+		{6, "https://youtu.be/ddddddddddd#t=30", "author=true"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("parsed %d lines, want %d: %+v", len(lines), len(want), lines)
+	}
+	for i, w := range want {
+		line := lines[i]
+		var overrides []string
+		for _, o := range line.Overrides {
+			overrides = append(overrides, o.Key+"="+o.Value)
+		}
+		if line.Number != w.number || line.Link != w.link || strings.Join(overrides, "|") != w.overrides {
+			t.Errorf("line %d = %d %s %q, want %d %s %q", i, line.Number, line.Link, overrides, w.number, w.link, w.overrides)
+		}
+	}
+}
+
+func TestParseBatchErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		// the first lines are fine, nothing may be downloaded before the mistake is found
+		{"unknown key", "https://youtu.be/aaaaaaaaaaa\nhttps://youtu.be/bbbbbbbbbbb quality=high\n", "line 2: unknown option"},
+		{"bad value", "https://youtu.be/aaaaaaaaaaa author=maybe\n", "line 1: author"},
+		{"no value", "https://youtu.be/aaaaaaaaaaa music\n", "line 1: expected key=value"},
+		{"open quote", "https://youtu.be/aaaaaaaaaaa template=\"{title}\n", "line 1: missing closing \""},
+	}
+	for _, tt := range tests {
+		lines, err := parseBatch(strings.NewReader(tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: parseBatch = %+v, %v, want an error with %q", tt.name, lines, err, tt.want)
+		}
+	}
+}
+
+func TestBatchApply(t *testing.T) {
+	root := t.TempDir()
+	opts := ytdl.DownloadOptions{OutputDir: root, Format: "720p"}
+
+	tests := []struct {
+		overrides []override
+		want      ytdl.DownloadOptions
+	}{
+		{nil, opts},
+		{[]override{{"dst", "music"}, {"template", "{title}"}},
+			ytdl.DownloadOptions{OutputDir: filepath.Join(root, "music"), Format: "720p", Template: "{title}"}},
+		// overrides apply in the order they are written
+		{[]override{{"format", "audio"}, {"audio-only", "false"}},
+			ytdl.DownloadOptions{OutputDir: root}},
+		{[]override{{"audio-only", "false"}, {"format", "audio"}},
+			ytdl.DownloadOptions{OutputDir: root, AudioOnly: true}},
+		{[]override{{"format", "18"}, {"format", "360p"}, {"video-only", "1"}, {"author", "true"}},
+			ytdl.DownloadOptions{OutputDir: root, Format: "360p", VideoOnly: true, IncludeAuthor: true}},
+	}
+	for _, tt := range tests {
+		// the same line gives the same options every time
+		for range 10 {
+			got, err := batchLine{Number: 1, Overrides: tt.overrides}.apply(opts)
+			if err != nil || got.OutputDir != tt.want.OutputDir || got.Format != tt.want.Format || got.Template != tt.want.Template ||
+				got.AudioOnly != tt.want.AudioOnly || got.VideoOnly != tt.want.VideoOnly || got.IncludeAuthor != tt.want.IncludeAuthor {
+				t.Errorf("apply(%v) = %+v, %v, want %+v", tt.overrides, got, err, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestBatchLinks(t *testing.T) {
+	lines := []batchLine{{Number: 1, Link: "https://youtu.be/bbbbbbbbbbb"}, {Number: 2, Link: "https://youtu.be/ccccccccccc"}}
+	links, err := batchLinks(lines, []string{"https://youtu.be/aaaaaaaaaaa"})
+	if err != nil || strings.Join(links, " ") != "https://youtu.be/aaaaaaaaaaa https://youtu.be/bbbbbbbbbbb https://youtu.be/ccccccccccc" {
+		t.Errorf("batchLinks = %q, %v", links, err)
+	}
+
+	lines[1].Overrides = []override{{"dst", "music"}}
+	if _, err := batchLinks(lines, nil); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("batchLinks with options = %v, want an error for line 2", err)
+	}
+}
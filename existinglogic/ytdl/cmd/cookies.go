@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/cookies"
+)
+
+// httpClientWithCookies returns the HTTP client to download with, sending the cookies
+// of --cookies when it's set, nil otherwise. save writes the cookies updated by the servers back to the file.
+func httpClientWithCookies(cmd *cobra.Command) (client *http.Client, save func(), err error) {
+	path, _ := cmd.Flags().GetString("cookies")
+	if path == "" {
+		return nil, func() {}, nil
+	}
+
+	jar, err := cookies.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	save = func() {
+		if err := jar.Save(path); err != nil {
+			cmd.PrintErrf("could not save cookies to %s: %v\n", path, err)
+		}
+	}
+	return &http.Client{Jar: jar}, save, nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCookiesFlag(t *testing.T) {
+	// every command downloading something may need to be signed in
+	for _, cmd := range []*cobra.Command{rootCmd, downloadCmd, infoCmd, syncCmd, watchCmd, daemonCmd, serveCmd, uiCmd} {
+		if cmd.Flags().Lookup("cookies") == nil {
+			t.Errorf("%s has no --cookies flag", cmd.Name())
+		}
+	}
+}
+
+func TestHTTPClientWithCookies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	jar := "# Netscape HTTP Cookie File\n.youtube.com\tTRUE\t/\tTRUE\t0\tSID\tsecret\n"
+	if err := os.WriteFile(path, []byte(jar), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("cookies", "", "")
+	client, save, err := httpClientWithCookies(cmd)
+	if client != nil || err != nil {
+		t.Errorf("without --cookies: client %v, %v, want none", client, err)
+	}
+	save()
+
+	cmd.Flags().Set("cookies", path)
+	client, save, err = httpClientWithCookies(cmd)
+	if err != nil || client == nil || client.Jar == nil {
+		t.Fatalf("client = %v, %v, want one with a jar", client, err)
+	}
+	youtube, _ := url.Parse("https://www.youtube.com/")
+	if cookies := client.Jar.Cookies(youtube); len(cookies) != 1 || cookies[0].Value != "secret" {
+		t.Errorf("cookies sent to youtube = %v", cookies)
+	}
+	save()
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/daemon"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/queue"
+)
+
+var stateDir string
+var socketPath string
+var concurrency int
+
+// daemonCmd keeps a durable queue of jobs and runs them in the background.
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run queued downloads in the background, controlled over a unix socket.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := queue.Open(filepath.Join(stateDir, "jobs"))
+		if err != nil {
+			return err
+		}
+		httpClient, saveCookies, err := httpClientWithCookies(cmd)
+		if err != nil {
+			return err
+		}
+		defer saveCookies()
+
+		client := ytdl.New(ytdl.Options{HTTPClient: httpClient, Log: cmd.OutOrStdout()})
+		server := &daemon.Server{
+			Store:       store,
+			SocketPath:  socketPath,
+			Concurrency: concurrency,
+			Log:         cmd.OutOrStdout(),
+			Run: func(ctx context.Context, job queue.Job) []error {
+				result, events := client.Download(ctx, job.Link, ytdl.DownloadOptions{
+					OutputDir:     job.OutputDir,
+					VideoOnly:     job.VideoOnly,
+					IncludeAuthor: job.IncludeAuthor,
+				})
+				for range events {
+				}
+				return result.Errors
+			},
+		}
+
+		cmd.Printf("Listening on %s with %d worker(s)\n", socketPath, concurrency)
+		return server.ListenAndServe(cmd.Context())
+	},
+}
+
+func init() {
+	defaultStateDir := defaultStateDir()
+	daemonCmd.Flags().StringVar(
+		&stateDir, "state-dir", defaultStateDir,
+		"Directory the job queue is persisted to.",
+	)
+	daemonCmd.Flags().IntVarP(
+		&concurrency, "concurrency", "c", 2,
+		"Maximum number of jobs downloaded at the same time.",
+	)
+	daemonCmd.Flags().String(
+		"cookies", "",
+		"Netscape cookies.txt file of a signed in browser session, updated cookies are written back to it.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&socketPath, "socket", filepath.Join(defaultStateDir, "ytdl.sock"),
+		"Unix socket the daemon listens on.",
+	)
+	rootCmd.AddCommand(daemonCmd)
+}
+
+// defaultStateDir is where the daemon keeps its queue unless told otherwise.
+func defaultStateDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ytdl")
+}
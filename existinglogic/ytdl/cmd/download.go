@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+)
+
+var downloadLinks []string
+
+// downloadCmd saves videos and playlists as they are streamed, without converting them to mp3.
+var downloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download videos and playlists, picking the format, file names and post-processing steps.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		jsonMode, progressMode := progressFlags(cmd)
+
+		httpClient, saveCookies, err := httpClientWithCookies(cmd)
+		if err != nil {
+			cmd.PrintErrln(err)
+			os.Exit(1)
+		}
+
+		// --links and the lines of --batch-file go through the same loop.
+		var lines []batchLine
+		for _, link := range downloadLinks {
+			lines = append(lines, batchLine{Link: link})
+		}
+		if batchFile, _ := cmd.Flags().GetString("batch-file"); batchFile != "" {
+			batch, err := readBatchFile(batchFile)
+			if err != nil {
+				cmd.PrintErrln(err)
+				os.Exit(1)
+			}
+			lines = append(lines, batch...)
+		}
+
+		// Metadata only modes, nothing gets downloaded.
+		dumpJSON, _ := cmd.Flags().GetBool("dump-json")
+		listFormats, _ := cmd.Flags().GetBool("list-formats")
+		if dumpJSON || listFormats {
+			videoOnly, _ := cmd.Flags().GetBool("video-only")
+			var infoLinks []string
+			for _, line := range lines {
+				infoLinks = append(infoLinks, line.Link)
+			}
+			client := ytdl.New(ytdl.Options{HTTPClient: httpClient})
+			err := printInfo(cmd.Context(), os.Stdout, client, infoLinks, ytdl.DownloadOptions{VideoOnly: videoOnly}, listFormats)
+			saveCookies()
+			if err != nil {
+				cmd.PrintErrln(err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		// the same video listed twice is only downloaded once
+		clientOpts := ytdl.Options{HTTPClient: httpClient, SkipDuplicates: true}
+		var output *jsonOutput
+		stopProgress := func() {}
+		if jsonMode {
+			output = newJSONOutput(os.Stdout)
+		} else {
+			clientOpts.Progress, clientOpts.Log, stopProgress = newReporter(progressMode, os.Stdout)
+		}
+		client := ytdl.New(clientOpts)
+		opts := downloadOptions(cmd)
+
+		// Handle links.
+		var errs []error
+		for _, line := range lines {
+			// don't start the next link once interrupted
+			if err := cmd.Context().Err(); err != nil {
+				errs = append(errs, err)
+				break
+			}
+
+			lineOpts, err := line.apply(opts)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			result, events := client.Download(cmd.Context(), line.Link, lineOpts)
+			for event := range events {
+				if jsonMode {
+					output.event(event)
+				}
+			}
+			errs = append(errs, result.Errors...)
+		}
+		saveCookies()
+		stopProgress()
+
+		if jsonMode {
+			output.summary(len(lines), errs)
+			if len(errs) > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+		exitWithErrors(cmd, errs)
+	},
+}
+
+func init() {
+	downloadCmd.Flags().StringSliceVarP(
+		&downloadLinks, "links", "l", []string{},
+		"List of video, playlist or media links which will be downloaded and saved on your local.",
+	)
+	downloadCmd.Flags().String(
+		"batch-file", "",
+		"File with one link per line (- for stdin), lines may add options like dst=music format=audio template=\"{index} - {title}\".",
+	)
+	downloadCmd.MarkFlagsOneRequired("links", "batch-file")
+	workingDir, _ := os.Getwd()
+	downloadCmd.Flags().StringP(
+		"dst", "d", workingDir,
+		"Output directory for downloaded files.",
+	)
+	downloadCmd.Flags().Bool(
+		"video-only", false,
+		"Download only the linked video even if the link points into a playlist.",
+	)
+	downloadCmd.Flags().Bool(
+		"author", false,
+		"Append the author to file and playlist folder names.",
+	)
+	downloadCmd.Flags().StringP(
+		"format", "f", "",
+		"Stream to download: an itag (18), a quality label (720p) or a quality (medium).",
+	)
+	downloadCmd.Flags().Bool(
+		"audio-only", false,
+		"Download the best audio only stream.",
+	)
+	downloadCmd.Flags().StringP(
+		"template", "t", "",
+		"File name template using {title}, {author}, {id} and {index}.",
+	)
+	downloadCmd.Flags().Bool(
+		"json", false,
+		"Print one JSON event per line instead of human readable output.",
+	)
+	downloadCmd.Flags().String(
+		"progress", "text",
+		"Progress output: text (dashboard on a terminal, log lines otherwise), dashboard, bars, log or ndjson (same as --json).",
+	)
+	downloadCmd.Flags().Bool(
+		"write-info-json", false,
+		"Write <name>.info.json with the metadata next to every download.",
+	)
+	downloadCmd.Flags().Bool(
+		"write-description", false,
+		"Write the video description to <name>.description next to every download.",
+	)
+	downloadCmd.Flags().Bool(
+		"write-playlist-metafiles", false,
+		"Write playlist.info.json into every playlist folder.",
+	)
+	addConflictFlag(downloadCmd)
+	addRestrictFilenamesFlag(downloadCmd)
+	addPostProcessFlags(downloadCmd)
+	downloadCmd.Flags().String(
+		"cookies", "",
+		"Netscape cookies.txt file of a signed in browser session, updated cookies are written back to it.",
+	)
+	downloadCmd.Flags().Bool(
+		"dump-json", false,
+		"Print the metadata of every link as JSON without downloading.",
+	)
+	downloadCmd.Flags().Bool(
+		"list-formats", false,
+		"Print the available formats of every link without downloading.",
+	)
+	rootCmd.AddCommand(downloadCmd)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+)
+
+// infoCmd prints metadata of links without downloading anything.
+var infoCmd = &cobra.Command{
+	Use:   "info <link>...",
+	Short: "Print the metadata and formats of videos or playlists as JSON.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		videoOnly, _ := cmd.Flags().GetBool("video-only")
+		listFormats, _ := cmd.Flags().GetBool("list-formats")
+		httpClient, saveCookies, err := httpClientWithCookies(cmd)
+		if err != nil {
+			return err
+		}
+		defer saveCookies()
+
+		client := ytdl.New(ytdl.Options{HTTPClient: httpClient})
+		opts := ytdl.DownloadOptions{VideoOnly: videoOnly}
+		return printInfo(cmd.Context(), cmd.OutOrStdout(), client, args, opts, listFormats)
+	},
+}
+
+func init() {
+	infoCmd.Flags().Bool(
+		"video-only", false,
+		"Only describe the linked video even if the link points into a playlist.",
+	)
+	infoCmd.Flags().Bool(
+		"list-formats", false,
+		"Print a human readable table of the available formats instead of JSON.",
+	)
+	infoCmd.Flags().String(
+		"cookies", "",
+		"Netscape cookies.txt file of a signed in browser session, updated cookies are written back to it.",
+	)
+	rootCmd.AddCommand(infoCmd)
+}
+
+// printInfo writes the metadata of every link, as JSON or as format tables.
+func printInfo(ctx context.Context, w io.Writer, client *ytdl.Client, links []string, opts ytdl.DownloadOptions, listFormats bool) error {
+	for _, link := range links {
+		videoInfo, playlistInfo, err := client.Info(ctx, link, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", link, err)
+		}
+
+		if !listFormats {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			if videoInfo != nil {
+				enc.Encode(videoInfo)
+			} else {
+				enc.Encode(playlistInfo)
+			}
+			continue
+		}
+
+		if videoInfo != nil {
+			printFormats(w, videoInfo)
+			continue
+		}
+
+		header := fmt.Sprintf("Playlist: %s - %s", playlistInfo.Title, playlistInfo.Author)
+		fmt.Fprintln(w, header)
+		fmt.Fprintln(w, strings.Repeat("=", len(header))+"\n")
+		for _, entry := range playlistInfo.Entries {
+			printFormats(w, &entry)
+		}
+	}
+	return nil
+}
+
+// printFormats writes the format table of a single video.
+func printFormats(w io.Writer, info *models.VideoInfo) {
+	duration := time.Duration(info.Duration * float64(time.Second))
+	if info.PlaylistIndex > 0 {
+		fmt.Fprintf(w, "(%d) ", info.PlaylistIndex)
+	}
+	fmt.Fprintf(w, "%s - %s [%s] %s\n", info.Title, info.Author, info.ID, duration)
+	if info.Error != "" {
+		fmt.Fprintf(w, "\tERROR: %s\n\n", info.Error)
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ITAG\tMIME\tQUALITY\tBITRATE\tSIZE\tAUDIO")
+	for _, format := range info.Formats {
+		quality := format.QualityLabel
+		if quality == "" {
+			quality = format.Quality
+		}
+		audio := "-"
+		if format.AudioChannels > 0 {
+			audio = fmt.Sprintf("%dch %s", format.AudioChannels, format.AudioQuality)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%dk\t%s\t%s\n",
+			format.Itag, format.MimeType, quality, format.Bitrate/1000, formatSize(format.ContentLength), audio)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+func formatSize(size int64) string {
+	if size <= 0 {
+		return "-"
+	}
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f%s", value, units[unit])
+}
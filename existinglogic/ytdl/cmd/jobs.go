@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/daemon"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/queue"
+)
+
+// addCmd hands links over to a running daemon instead of downloading them.
+var addCmd = &cobra.Command{
+	Use:   "add <link>...",
+	Short: "Enqueue links on the running daemon.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dstDir, _ := cmd.Flags().GetString("dst")
+		// the daemon runs in another directory
+		dstDir, err := filepath.Abs(dstDir)
+		if err != nil {
+			return err
+		}
+		videoOnly, _ := cmd.Flags().GetBool("video-only")
+		includeAuthor, _ := cmd.Flags().GetBool("author")
+
+		for _, link := range args {
+			jobs, err := daemon.Call(socketPath, daemon.Request{
+				Op: daemon.OpEnqueue,
+				Job: &queue.Job{
+					Link:          link,
+					OutputDir:     dstDir,
+					VideoOnly:     videoOnly,
+					IncludeAuthor: includeAuthor,
+				},
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Queued %s as job %s\n", link, jobs[0].ID)
+		}
+		return nil
+	},
+}
+
+// jobsCmd lists every job known to the daemon.
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "List jobs of the running daemon.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jobs, err := daemon.Call(socketPath, daemon.Request{Op: daemon.OpList})
+		if err != nil {
+			return err
+		}
+		printJobs(jobs)
+		return nil
+	},
+}
+
+var cancelCmd = &cobra.Command{
+	Use:   "cancel <job id>",
+	Short: "Cancel a queued or running job.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jobs, err := daemon.Call(socketPath, daemon.Request{Op: daemon.OpCancel, ID: args[0]})
+		if err != nil {
+			return err
+		}
+		printJobs(jobs)
+		return nil
+	},
+}
+
+var retryCmd = &cobra.Command{
+	Use:   "retry <job id>",
+	Short: "Queue a failed or canceled job again.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jobs, err := daemon.Call(socketPath, daemon.Request{Op: daemon.OpRetry, ID: args[0]})
+		if err != nil {
+			return err
+		}
+		printJobs(jobs)
+		return nil
+	},
+}
+
+func init() {
+	workingDir, _ := os.Getwd()
+	addCmd.Flags().StringP(
+		"dst", "d", workingDir,
+		"Output directory for downloaded files.",
+	)
+	addCmd.Flags().Bool(
+		"video-only", false,
+		"Download only the linked video even if the link points into a playlist.",
+	)
+	addCmd.Flags().Bool(
+		"author", false,
+		"Append the author to file and playlist folder names.",
+	)
+	rootCmd.AddCommand(addCmd, jobsCmd, cancelCmd, retryCmd)
+}
+
+func printJobs(jobs []queue.Job) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tATTEMPTS\tLINK\tERRORS")
+	for _, job := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
+			job.ID, job.Status, job.Attempts, job.Link, strings.Join(job.Errors, "; "))
+	}
+	w.Flush()
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/downloader"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/progress"
+)
+
+// summary closes a --json run.
+type summary struct {
+	Type    string   `json:"type"`
+	Links   int      `json:"links"`
+	Done    int      `json:"done"`
+	Skipped int      `json:"skipped"`
+	Failed  int      `json:"failed"`
+	Bytes   int64    `json:"bytes"`
+	Elapsed float64  `json:"elapsed"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// jsonOutput writes downloader events as newline delimited JSON
+// and keeps the totals needed by the closing summary.
+type jsonOutput struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	start time.Time
+	done  int
+	skip  int
+	fail  int
+	bytes int64
+}
+
+func newJSONOutput(w io.Writer) *jsonOutput {
+	return &jsonOutput{enc: json.NewEncoder(w), start: time.Now()}
+}
+
+func (o *jsonOutput) event(event downloader.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch event.Type {
+	case downloader.EventDone:
+		o.done++
+		o.bytes += event.Bytes
+	case downloader.EventSkipped:
+		o.skip++
+	case downloader.EventError:
+		o.fail++
+	}
+	o.enc.Encode(event)
+}
+
+func (o *jsonOutput) summary(links int, errs []error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := summary{
+		Type:    "summary",
+		Links:   links,
+		Done:    o.done,
+		Skipped: o.skip,
+		Failed:  o.fail,
+		Bytes:   o.bytes,
+		Elapsed: time.Since(o.start).Seconds(),
+	}
+	for _, err := range errs {
+		s.Errors = append(s.Errors, err.Error())
+	}
+	o.enc.Encode(s)
+}
+
+// pipelineEvents writes the steps of the mp3 pipeline of the video package as downloader events.
+// handleLinks emits the event of every step, while as the Progress of the video package
+// it adds progress events repeating the last event of the video.
+type pipelineEvents struct {
+	output *jsonOutput
+
+	mu     sync.Mutex
+	videos map[string]downloader.Event
+}
+
+func newPipelineEvents(output *jsonOutput) *pipelineEvents {
+	return &pipelineEvents{output: output, videos: make(map[string]downloader.Event)}
+}
+
+func (p *pipelineEvents) emit(event downloader.Event) {
+	event.Time = time.Now()
+	p.mu.Lock()
+	p.videos[event.Link] = event
+	p.mu.Unlock()
+	p.output.event(event)
+}
+
+func (p *pipelineEvents) Start(id string, name string, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	event := p.videos[id]
+	event.Link, event.Total = id, total
+	p.videos[id] = event
+}
+
+// Bytes is called at most every progress.ReportInterval by the video package.
+func (p *pipelineEvents) Bytes(id string, n int64) {
+	p.mu.Lock()
+	event := p.videos[id]
+	p.mu.Unlock()
+
+	event.Type, event.Time, event.Bytes = downloader.EventProgress, time.Now(), n
+	p.output.event(event)
+}
+
+// Stage, Done and Error are left to handleLinks, which knows the video and the file.
+func (p *pipelineEvents) Stage(id string, stage string) {}
+
+func (p *pipelineEvents) Done(id string) {}
+
+func (p *pipelineEvents) Error(id string, err error) {}
+
+// progressFlags reads --json and --progress, exiting when the mode is unknown.
+// --progress=ndjson turns jsonMode on.
+func progressFlags(cmd *cobra.Command) (jsonMode bool, mode string) {
+	jsonMode, _ = cmd.Flags().GetBool("json")
+	mode, _ = cmd.Flags().GetString("progress")
+	switch mode {
+	case "text", "dashboard", "bars", "log":
+	case "ndjson":
+		jsonMode = true
+	default:
+		cmd.PrintErrf("unknown --progress mode %q, expected text, dashboard, bars, log or ndjson\n", mode)
+		os.Exit(1)
+	}
+	return jsonMode, mode
+}
+
+// newReporter returns the progress reporter of a --progress mode writing to out,
+// the writer the rest of the output goes through and a func to call once downloads are over.
+func newReporter(mode string, out *os.File) (progress.Reporter, io.Writer, func()) {
+	if mode == "text" {
+		mode = "log"
+		if isTerminal(out) {
+			mode = "dashboard"
+		}
+	}
+
+	switch mode {
+	case "dashboard":
+		dashboard := progress.NewDashboard(out)
+		return dashboard, dashboard.Bypass(), dashboard.Stop
+	case "bars":
+		bars := progress.NewBars(out)
+		return bars, bars.Bypass(), bars.Stop
+	default:
+		return progress.NewLog(out), out, func() {}
+	}
+}
+
+// isTerminal tells whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -1,14 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
-	"github.com/gosuri/uiprogress"
 	"github.com/spf13/cobra"
 
-	"ytdl/downloader"
-	"ytdl/video"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/downloader"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/postprocess"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/rootpath"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/video"
 )
 
 /*
@@ -29,22 +34,59 @@ var rootCmd = &cobra.Command{
 	Long:      "Command line tool for converting YouTube videos to mp3/mp4 files. ",
 	Args:      cobra.OnlyValidArgs,
 	ValidArgs: []string{"names", "links", "ytdl"},
+	// Subcommands return runtime errors, those don't need the usage text.
+	SilenceUsage: true,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Init progress bar.
-		uiprogress.Start()
+		jsonMode, progressMode := progressFlags(cmd)
+
+		httpClient, saveCookies, err := httpClientWithCookies(cmd)
+		if err != nil {
+			cmd.PrintErrln(err)
+			os.Exit(1)
+		}
+		if httpClient != nil {
+			video.HTTPClient = httpClient
+		}
+		video.PostProcess = postProcessChain(cmd)
+		video.Output = conflictPolicy(cmd).Output
+
+		// the links of --batch-file come after --links
+		if batchFile, _ := cmd.Flags().GetString("batch-file"); batchFile != "" {
+			batch, err := readBatchFile(batchFile)
+			if err == nil {
+				links, err = batchLinks(batch, links)
+			}
+			if err != nil {
+				cmd.PrintErrln(err)
+				os.Exit(1)
+			}
+		}
+
+		// Report progress as bars or log lines, or as JSON events without any bar.
+		emit := func(downloader.Event) {}
+		stopProgress := func() {}
+		var output *jsonOutput
+		if jsonMode {
+			output = newJSONOutput(os.Stdout)
+			events := newPipelineEvents(output)
+			video.Progress, video.Log, emit = events, io.Discard, events.emit
+		} else {
+			video.Progress, video.Log, stopProgress = newReporter(progressMode, os.Stdout)
+		}
+
 		// Handle links.
-		errs := handleLinks(cmd, links)
-		// Stdout errors and exit.
-		if len(errs) > 0 {
-			cmd.Printf("\nThe following issues occurred during execution:\n")
-			for _, err := range errs {
-				cmd.Printf(" - %v\n", err)
+		errs := handleLinks(cmd, links, emit)
+		saveCookies()
+		stopProgress()
+
+		if jsonMode {
+			output.summary(len(links), errs)
+			if len(errs) > 0 {
+				os.Exit(1)
 			}
-			cmd.Printf(
-				"\nErrors encountered\n",
-			)
-			os.Exit(1)
+			return
 		}
+		exitWithErrors(cmd, errs)
 	},
 }
 
@@ -54,18 +96,81 @@ func init() {
 		&links, "links", "l", []string{},
 		"List of YouTube video links which will be converted to mp3 and saved on your local.",
 	)
-	rootCmd.MarkFlagRequired("links")
+	rootCmd.Flags().String(
+		"batch-file", "",
+		"File with one link per line (- for stdin). Per line options like dst=music need the download command.",
+	)
+	rootCmd.MarkFlagsOneRequired("links", "batch-file")
 	workingDir, _ := os.Getwd()
 	rootCmd.Flags().StringP(
 		"dst", "d", workingDir,
 		"Output directory for mp3 files.",
 	)
+	rootCmd.Flags().Bool(
+		"json", false,
+		"Print one JSON event per line instead of human readable output.",
+	)
+	rootCmd.Flags().String(
+		"progress", "text",
+		"Progress output: text (dashboard on a terminal, log lines otherwise), dashboard, bars, log or ndjson (same as --json).",
+	)
+	addPostProcessFlags(rootCmd)
+	addConflictFlag(rootCmd)
+	rootCmd.Flags().String(
+		"cookies", "",
+		"Netscape cookies.txt file of a signed in browser session, updated cookies are written back to it.",
+	)
+}
+
+// downloadOptions reads the download flags shared by commands running the downloader.
+func downloadOptions(cmd *cobra.Command) ytdl.DownloadOptions {
+	dstDir, _ := cmd.Flags().GetString("dst")
+	videoOnly, _ := cmd.Flags().GetBool("video-only")
+	includeAuthor, _ := cmd.Flags().GetBool("author")
+	format, _ := cmd.Flags().GetString("format")
+	audioOnly, _ := cmd.Flags().GetBool("audio-only")
+	template, _ := cmd.Flags().GetString("template")
+	writeInfoJSON, _ := cmd.Flags().GetBool("write-info-json")
+	writeDescription, _ := cmd.Flags().GetBool("write-description")
+	writePlaylistMetafiles, _ := cmd.Flags().GetBool("write-playlist-metafiles")
+	filenameProfile := rootpath.ProfileNative
+	if flag := cmd.Flags().Lookup("restrict-filenames"); flag != nil {
+		filenameProfile = rootpath.Profile(flag.Value.String())
+	}
+
+	return ytdl.DownloadOptions{
+		OutputDir:     dstDir,
+		VideoOnly:     videoOnly,
+		IncludeAuthor: includeAuthor,
+		Format:        format,
+		AudioOnly:     audioOnly,
+		Template:      template,
+
+		WriteInfoJSON:          writeInfoJSON,
+		WriteDescription:       writeDescription,
+		WritePlaylistMetafiles: writePlaylistMetafiles,
+
+		OnConflict:      conflictPolicy(cmd),
+		FilenameProfile: filenameProfile,
+		PostProcess:     postProcessChain(cmd),
+	}
+}
+
+// postProcessChain returns the steps of --post-process and its shortcuts, nil when the command has none.
+func postProcessChain(cmd *cobra.Command) postprocess.Chain {
+	flag := cmd.Flags().Lookup("post-process")
+	if flag == nil {
+		return nil
+	}
+	// the steps were checked while parsing the flags
+	chain, _ := postprocess.ParseChain(*flag.Value.(*postProcessFlag).steps)
+	return chain
 }
 
 // Execute This is called by main.main().
 // It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	err := rootCmd.ExecuteContext(interruptContext())
 	if err != nil {
 		os.Exit(1)
 	}
@@ -81,48 +186,48 @@ func Execute() {
 // - Downloads videos and saves them in temp files.
 // - ffmpeg magic.
 // - Cleans up tmp files.
-func handleLinks(cmd *cobra.Command, links []string) []error {
-	// Validate links. If at least one link is not valid we stop an execution.
-	errs := video.ValidateLinks(links)
-	if len(errs) > 0 {
-		return errs
-	}
+//
+// Every step of every video is passed to emit as a downloader event, for --json.
+// Videos failing a step don't go through the next ones.
+func handleLinks(cmd *cobra.Command, links []string, emit func(downloader.Event)) []error {
+	ctx := cmd.Context()
 
+	// Validate links. If at least one link is not valid we stop an execution.
 	var errors []error
+	for _, link := range links {
+		for _, err := range video.ValidateLinks([]string{link}) {
+			errors = append(errors, err)
+			emit(downloader.Event{Type: downloader.EventError, Link: link, Error: err.Error()})
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	for _, link := range links {
+		emit(downloader.Event{Type: downloader.EventQueued, Link: link})
+	}
 
-	videos := fetchPlaybackURLS(links, &errors)
+	videos := fetchPlaybackURLS(ctx, links, emit, &errors)
 
 	// Fetch metadata.
 	channelFetchMetadata := make(chan video.ChannelMessage, len(videos))
 	for _, _video := range videos {
-		go video.FetchMetadata(_video, channelFetchMetadata)
+		go video.FetchMetadata(ctx, _video, channelFetchMetadata)
 	}
-	for i := 0; i < len(videos); i++ {
-		msg := <-channelFetchMetadata
-		if msg.Error != nil {
-			errors = append(errors, msg.Error)
-		}
-	}
-	close(channelFetchMetadata)
+	videos = collect(channelFetchMetadata, len(videos), emit, &errors, func(v *video.Video) {
+		emit(videoEvent(downloader.EventMetadata, v))
+	})
 
 	// Download and save temp video files.
 	channelFetchVideo := make(chan video.ChannelMessage, len(videos))
 	for _, _video := range videos {
-		go video.FetchVideo(_video, channelFetchVideo)
-	}
-	for i := 0; i < len(videos); i++ {
-		msg := <-channelFetchVideo
-		if msg.Error != nil {
-			errors = append(errors, msg.Error)
-		}
+		go video.FetchVideo(ctx, _video, channelFetchVideo)
 	}
+	videos = collect(channelFetchVideo, len(videos), emit, &errors, nil)
 	// Cleanup file when main function is over.
 	defer func(videos []*video.Video) {
 		for _, v := range videos {
-			err := os.Remove((*v.File).Name())
-			if err != nil {
-				errors = append(errors, err)
-			}
+			os.Remove((*v.File).Name())
 		}
 	}(videos)
 
@@ -130,20 +235,22 @@ func handleLinks(cmd *cobra.Command, links []string) []error {
 	dstDir, _ := cmd.Flags().GetString("dst")
 	channelConvertVideoToAudio := make(chan video.ChannelMessage, len(videos))
 	for _, _video := range videos {
-		go video.ConvertVideoToAudio(_video, dstDir, channelConvertVideoToAudio)
+		go video.ConvertVideoToAudio(ctx, _video, dstDir, channelConvertVideoToAudio)
 	}
-	for i := 0; i < len(videos); i++ {
-		msg := <-channelConvertVideoToAudio
-		if msg.Error != nil {
-			errors = append(errors, msg.Error)
+	collect(channelConvertVideoToAudio, len(videos), emit, &errors, func(v *video.Video) {
+		event := videoEvent(downloader.EventDone, v)
+		event.Path = v.AudioFilePath
+		if info, err := os.Stat(v.AudioFilePath); err == nil {
+			event.Bytes, event.Total = info.Size(), info.Size()
 		}
-	}
+		emit(event)
+	})
 
 	return errors
 }
 
 // allow video.Video to support video.Playlist
-func fetchPlaybackURLS(links []string, errors *[]error) []*video.Video {
+func fetchPlaybackURLS(ctx context.Context, links []string, emit func(downloader.Event), errors *[]error) []*video.Video {
 
 	var videos []*video.Video
 
@@ -151,7 +258,7 @@ func fetchPlaybackURLS(links []string, errors *[]error) []*video.Video {
 
 	for _, link := range links {
 		// Start go runtime thread.
-		go video.FetchPlaybackURL(link, channelFetchPlaybackURL)
+		go video.FetchPlaybackURL(ctx, link, channelFetchPlaybackURL)
 	}
 
 	for i := 0; i < len(links); i++ {
@@ -159,6 +266,7 @@ func fetchPlaybackURLS(links []string, errors *[]error) []*video.Video {
 		msg := <-channelFetchPlaybackURL
 		if msg.Error != nil {
 			*errors = append(*errors, msg.Error)
+			emit(downloader.Event{Type: downloader.EventError, Link: msg.Link, Error: msg.Error.Error()})
 		} else if msg.Result.HasStreamURL() {
 			videos = append(videos, msg.Result)
 		}
@@ -168,6 +276,52 @@ func fetchPlaybackURLS(links []string, errors *[]error) []*video.Video {
 	return videos
 }
 
+// collect waits for the n messages of a step and returns the videos that went through it,
+// passing them to done when it isn't nil. Failures are added to errors.
+func collect(results chan video.ChannelMessage, n int, emit func(downloader.Event), errors *[]error, done func(*video.Video)) []*video.Video {
+	var videos []*video.Video
+	for i := 0; i < n; i++ {
+		msg := <-results
+		if msg.Error != nil {
+			*errors = append(*errors, msg.Error)
+			emit(downloader.Event{Type: downloader.EventError, Link: msg.Link, Error: msg.Error.Error()})
+			continue
+		}
+		videos = append(videos, msg.Result)
+		if done != nil {
+			done(msg.Result)
+		}
+	}
+	close(results)
+	return videos
+}
+
+// videoEvent describes v for --json.
+func videoEvent(eventType downloader.EventType, v *video.Video) downloader.Event {
+	return downloader.Event{
+		Type:     eventType,
+		Link:     v.URL(),
+		VideoID:  v.ID(),
+		Title:    v.Title(),
+		Duration: v.Duration().Seconds(),
+	}
+}
+
+// exitWithErrors prints errs and exits, it returns when there are none.
+func exitWithErrors(cmd *cobra.Command, errs []error) {
+	if len(errs) == 0 {
+		return
+	}
+	cmd.Printf("\nThe following issues occurred during execution:\n")
+	for _, err := range errs {
+		cmd.Printf(" - %v\n", err)
+	}
+	cmd.Printf(
+		"\nErrors encountered\n",
+	)
+	os.Exit(1)
+}
+
 func RunTestDownload() {
 	// vidlink := "https://www.youtube.com/watch?v=N4-Sk506pgA&list=PLWU88pbzc3rMYGfcVxDVvsQIqTsizJ0YL&index=154&ab_channel=victorpardo"
 	playlistLink := "https://www.youtube.com/watch?v=_i1wNr2gEVo&list=PLWU88pbzc3rMYGfcVxDVvsQIqTsizJ0YL&ab_channel=rumworld"
@@ -175,11 +329,135 @@ func RunTestDownload() {
 	videoOnly := false
 	includeAuthor := false
 
-	errs := downloader.DownloadLink(playlistLink, testOutputDir, videoOnly, includeAuthor)
-	if len(errs) > 0 {
+	client := ytdl.New(ytdl.Options{Log: os.Stdout})
+	result, events := client.Download(context.Background(), playlistLink, ytdl.DownloadOptions{
+		OutputDir:     testOutputDir,
+		VideoOnly:     videoOnly,
+		IncludeAuthor: includeAuthor,
+	})
+	for range events {
+	}
+	if len(result.Errors) > 0 {
 		fmt.Println("errors encountered")
-		for i, err := range errs {
+		for i, err := range result.Errors {
 			fmt.Printf("\terror (%d): '%s'\n", i+1, err.Error())
 		}
 	}
 }
+
+// conflictFlag is the --on-conflict value, it's checked while parsing the flags.
+type conflictFlag downloader.ConflictPolicy
+
+func (f *conflictFlag) String() string {
+	return string(*f)
+}
+
+func (f *conflictFlag) Set(value string) error {
+	switch policy := downloader.ConflictPolicy(value); policy {
+	case downloader.ConflictSkip, downloader.ConflictOverwrite, downloader.ConflictRename, downloader.ConflictError:
+		*f = conflictFlag(policy)
+		return nil
+	default:
+		return fmt.Errorf("expected skip, overwrite, rename or error")
+	}
+}
+
+func (f *conflictFlag) Type() string {
+	return "policy"
+}
+
+// addConflictFlag defines --on-conflict on a command running the downloader.
+func addConflictFlag(cmd *cobra.Command) {
+	policy := conflictFlag(downloader.ConflictSkip)
+	cmd.Flags().Var(
+		&policy, "on-conflict",
+		"When the output file exists: skip (when it's complete, replace it otherwise), overwrite, rename or error.",
+	)
+}
+
+// conflictPolicy reads --on-conflict, commands without it skip complete files.
+func conflictPolicy(cmd *cobra.Command) downloader.ConflictPolicy {
+	if flag := cmd.Flags().Lookup("on-conflict"); flag != nil {
+		return downloader.ConflictPolicy(flag.Value.String())
+	}
+	return downloader.ConflictSkip
+}
+
+// profileFlag is the --restrict-filenames value, it's checked while parsing the flags.
+type profileFlag rootpath.Profile
+
+func (f *profileFlag) String() string {
+	return string(*f)
+}
+
+func (f *profileFlag) Set(value string) error {
+	profile, err := rootpath.ParseProfile(value)
+	if err != nil {
+		return err
+	}
+	*f = profileFlag(profile)
+	return nil
+}
+
+func (f *profileFlag) Type() string {
+	return "profile"
+}
+
+// addRestrictFilenamesFlag defines --restrict-filenames on a command running the downloader.
+func addRestrictFilenamesFlag(cmd *cobra.Command) {
+	profile := profileFlag(rootpath.ProfileNative)
+	cmd.Flags().Var(
+		&profile, "restrict-filenames",
+		"Name files for another filesystem whatever the host: windows, fat32, ascii or posix.",
+	)
+}
+
+// postProcessFlag adds the steps of --post-process, --exec, --remux-video and --recode-video
+// to a shared list, keeping the order they are given in. Each step is checked while parsing the flags.
+type postProcessFlag struct {
+	steps  *[]string
+	prefix string
+	kind   string
+}
+
+func (f *postProcessFlag) String() string {
+	return strings.Join(*f.steps, ",")
+}
+
+func (f *postProcessFlag) Set(value string) error {
+	step := f.prefix + value
+	if _, err := postprocess.Parse(step); err != nil {
+		return err
+	}
+	*f.steps = append(*f.steps, step)
+	return nil
+}
+
+func (f *postProcessFlag) Type() string {
+	return f.kind
+}
+
+// addPostProcessFlags defines --post-process and its shortcuts on a command running the downloader.
+func addPostProcessFlags(cmd *cobra.Command) {
+	steps := new([]string)
+	cmd.Flags().Var(
+		&postProcessFlag{steps: steps, kind: "step"}, "post-process",
+		"Run a step on every saved file, repeat it for more steps run in order: extract-audio[:mp3|m4a|opus|flac|wav], "+
+			"remux:<container>, recode:<codec>, embed-thumbnail, tag, exec:<command>, move:<dir> or webhook:<url>. ffmpeg does the conversions.",
+	)
+	cmd.Flags().Var(
+		&postProcessFlag{steps: steps, prefix: "exec:", kind: "command"}, "exec",
+		"Run a shell command on every saved file, same as --post-process exec:<command>. "+
+			"It may use {path}, {dir}, {name}, {title}, {author}, {id}, {url} and {index}, "+
+			"which stand for quoted variables like \"$YTDL_TITLE\" (\"!YTDL_TITLE!\" on Windows). Don't quote them, write \"Saved $YTDL_TITLE\" inside a longer string.",
+	)
+	cmd.Flags().Var(
+		&postProcessFlag{steps: steps, prefix: "remux:", kind: "container"}, "remux-video",
+		"Copy the streams of every saved file into an mp4, mkv, mov or webm file, same as --post-process remux:<container>.",
+	)
+	cmd.Flags().Var(
+		&postProcessFlag{steps: steps, prefix: "recode:", kind: "codec"}, "recode-video",
+		"Re-encode every saved file with h264, h265, vp9 or av1, optionally followed by :crf=<n> and a resolution "+
+			"(360p to 2160p), e.g. h265:crf=26:720p. Same as --post-process recode:<codec>.",
+	)
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/server"
+)
+
+// serveCmd exposes the downloader over a REST API.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a REST API to submit and monitor downloads.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		dstDir, _ := cmd.Flags().GetString("dst")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		httpClient, saveCookies, err := httpClientWithCookies(cmd)
+		if err != nil {
+			return err
+		}
+		defer saveCookies()
+
+		srv := server.New(dstDir, concurrency)
+		srv.HTTPClient = httpClient
+		cmd.Printf("Serving downloads from %s on %s\n", dstDir, addr)
+		return listenAndServe(cmd.Context(), addr, srv.Handler(), srv)
+	},
+}
+
+func init() {
+	workingDir, _ := os.Getwd()
+	serveCmd.Flags().String(
+		"addr", "localhost:8080",
+		"Address the API listens on.",
+	)
+	serveCmd.Flags().StringP(
+		"dst", "d", workingDir,
+		"Root directory downloads are saved to and served from.",
+	)
+	serveCmd.Flags().IntP(
+		"concurrency", "c", 2,
+		"Maximum number of jobs downloaded at the same time.",
+	)
+	serveCmd.Flags().String(
+		"cookies", "",
+		"Netscape cookies.txt file of a signed in browser session, updated cookies are written back to it.",
+	)
+	rootCmd.AddCommand(serveCmd)
+}
+
+// listenAndServe serves handler on addr until ctx is canceled,
+// then stops accepting requests and cancels the jobs of srv.
+func listenAndServe(ctx context.Context, addr string, handler http.Handler, srv *server.Server) error {
+	httpServer := &http.Server{Addr: addr, Handler: handler}
+
+	go func() {
+		<-ctx.Done()
+		// event streams never finish on their own, don't wait for them
+		httpServer.Close()
+	}()
+
+	err := httpServer.ListenAndServe()
+	srv.Close()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptContext is canceled by the first Ctrl-C so downloads can stop
+// and remove their partial files, a second Ctrl-C exits right away.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Fprintln(os.Stderr, "\nInterrupted, cleaning up. Press Ctrl-C again to exit immediately.")
+		cancel()
+
+		<-signals
+		os.Exit(130)
+	}()
+
+	return ctx
+}
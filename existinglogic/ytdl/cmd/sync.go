@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/downloader"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+)
+
+// syncCmd keeps a local folder matching a playlist.
+var syncCmd = &cobra.Command{
+	Use:   "sync <playlist> <dir>",
+	Short: "Keep a folder in sync with a playlist: download additions, renumber, move removals.",
+	Long: "Keep a folder in sync with a playlist: download additions, renumber, move removals.\n\n" +
+		"Files are named \"{index} - {title}\" unless --template is given, the state of the\n" +
+		"playlist folder is kept in its " + downloader.SyncManifestFileName + " file.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		moveRemoved, _ := cmd.Flags().GetBool("remove")
+		httpClient, saveCookies, err := httpClientWithCookies(cmd)
+		if err != nil {
+			return err
+		}
+		defer saveCookies()
+
+		client := ytdl.New(ytdl.Options{HTTPClient: httpClient, Log: cmd.OutOrStdout()})
+		opts := downloadOptions(cmd)
+		opts.OutputDir = args[1]
+
+		result, events := client.Sync(cmd.Context(), args[0], opts, ytdl.SyncOptions{
+			MoveRemoved: moveRemoved,
+		})
+		for range events {
+		}
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("%d issue(s) occurred during the sync", len(result.Errors))
+		}
+		return nil
+	},
+}
+
+func init() {
+	syncCmd.Flags().Bool(
+		"remove", false,
+		"Move files of entries removed from the playlist to "+downloader.RemovedFolderName+"/.",
+	)
+	syncCmd.Flags().Bool(
+		"author", false,
+		"Append the author to the playlist folder name.",
+	)
+	syncCmd.Flags().StringP(
+		"format", "f", "",
+		"Stream to download: an itag (18), a quality label (720p) or a quality (medium).",
+	)
+	syncCmd.Flags().Bool(
+		"audio-only", false,
+		"Download the best audio only stream.",
+	)
+	syncCmd.Flags().StringP(
+		"template", "t", "",
+		"File name template using {title}, {author}, {id} and {index}.",
+	)
+	syncCmd.Flags().Bool(
+		"write-info-json", false,
+		"Write <name>.info.json with the metadata next to every download.",
+	)
+	syncCmd.Flags().Bool(
+		"write-playlist-metafiles", false,
+		"Write playlist.info.json into the playlist folder.",
+	)
+	addConflictFlag(syncCmd)
+	addRestrictFilenamesFlag(syncCmd)
+	addPostProcessFlags(syncCmd)
+	syncCmd.Flags().String(
+		"cookies", "",
+		"Netscape cookies.txt file of a signed in browser session, updated cookies are written back to it.",
+	)
+	rootCmd.AddCommand(syncCmd)
+}
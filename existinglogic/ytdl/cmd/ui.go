@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/server"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/webui"
+)
+
+// uiCmd serves the browser UI on top of the REST API.
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Start a local web UI to submit and watch downloads.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		dstDir, _ := cmd.Flags().GetString("dst")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		httpClient, saveCookies, err := httpClientWithCookies(cmd)
+		if err != nil {
+			return err
+		}
+		defer saveCookies()
+
+		srv := server.New(dstDir, concurrency)
+		srv.HTTPClient = httpClient
+		cmd.Printf("Open http://%s in your browser\n", addr)
+		return listenAndServe(cmd.Context(), addr, webui.Handler(srv), srv)
+	},
+}
+
+func init() {
+	workingDir, _ := os.Getwd()
+	uiCmd.Flags().String(
+		"addr", "localhost:8081",
+		"Address the UI listens on.",
+	)
+	uiCmd.Flags().StringP(
+		"dst", "d", workingDir,
+		"Root directory downloads are saved to and served from.",
+	)
+	uiCmd.Flags().IntP(
+		"concurrency", "c", 2,
+		"Maximum number of jobs downloaded at the same time.",
+	)
+	uiCmd.Flags().String(
+		"cookies", "",
+		"Netscape cookies.txt file of a signed in browser session, updated cookies are written back to it.",
+	)
+	rootCmd.AddCommand(uiCmd)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/downloader"
+)
+
+// verifyCmd checks downloaded files for truncated or corrupt media.
+var verifyCmd = &cobra.Command{
+	Use:   "verify <dir>",
+	Short: "Check the media files under a folder with ffprobe and report broken ones.",
+	Long: "Check the media files under a folder with ffprobe and report broken ones.\n\n" +
+		"Files with a .info.json sidecar are also compared to the size and duration it records,\n" +
+		"leftover .part files of interrupted downloads are reported as incomplete.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := exec.LookPath("ffprobe"); err != nil {
+			return fmt.Errorf("verify needs ffprobe from ffmpeg in the PATH: %w", err)
+		}
+
+		checked, broken := 0, 0
+		err := filepath.WalkDir(args[0], func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || !downloader.IsVerifiable(path) {
+				return nil
+			}
+
+			checked++
+			if err := downloader.VerifyFile(cmd.Context(), path); err != nil {
+				if cmd.Context().Err() != nil {
+					return cmd.Context().Err()
+				}
+				var corrupt *downloader.ErrorCorrupt
+				if !errors.As(err, &corrupt) {
+					return err
+				}
+				broken++
+				cmd.Printf("BROKEN %v\n", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		cmd.Printf("Checked %d file(s), %d broken\n", checked, broken)
+		if broken > 0 {
+			return fmt.Errorf("%d broken file(s) found", broken)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/rootpath"
+)
+
+// watchCmd downloads the links of files dropped into an inbox folder.
+var watchCmd = &cobra.Command{
+	Use:   "watch <dir>",
+	Short: "Download the links of .txt and .url files dropped into a folder.",
+	Long: "Download the links of .txt and .url files dropped into a folder.\n\n" +
+		"Every http(s) link of a .txt file is downloaded with the key=value options of the\n" +
+		"download --batch-file format found on its line, dst= stays inside --dst.\n" +
+		".url files are Internet Shortcuts.\n" +
+		"Processed files are moved to done/ or failed/ next to a .log of their downloads.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		httpClient, saveCookies, err := httpClientWithCookies(cmd)
+		if err != nil {
+			return err
+		}
+		defer saveCookies()
+
+		opts := downloadOptions(cmd)
+		inbox := &inbox{dir: args[0], httpClient: httpClient, opts: opts, out: cmd.OutOrStdout()}
+		cmd.Printf("Watching %s, downloading to %s\n", inbox.dir, opts.OutputDir)
+		return inbox.watch(cmd.Context(), interval)
+	},
+}
+
+func init() {
+	dstDir := rootpath.GetRootPath()
+	if dstDir == "" {
+		dstDir, _ = os.Getwd()
+	}
+	watchCmd.Flags().StringP(
+		"dst", "d", dstDir,
+		"Output directory for downloaded files, per-line dst= options are relative to it.",
+	)
+	watchCmd.Flags().Duration(
+		"interval", 2*time.Second,
+		"How often the folder is checked for new files.",
+	)
+	watchCmd.Flags().Bool(
+		"video-only", false,
+		"Download only the linked video even if the link points into a playlist.",
+	)
+	watchCmd.Flags().Bool(
+		"author", false,
+		"Append the author to file and playlist folder names.",
+	)
+	watchCmd.Flags().StringP(
+		"format", "f", "",
+		"Stream to download: an itag (18), a quality label (720p) or a quality (medium).",
+	)
+	watchCmd.Flags().Bool(
+		"audio-only", false,
+		"Download the best audio only stream.",
+	)
+	watchCmd.Flags().StringP(
+		"template", "t", "",
+		"File name template using {title}, {author}, {id} and {index}.",
+	)
+	addConflictFlag(watchCmd)
+	addRestrictFilenamesFlag(watchCmd)
+	addPostProcessFlags(watchCmd)
+	watchCmd.Flags().String(
+		"cookies", "",
+		"Netscape cookies.txt file of a signed in browser session, updated cookies are written back to it.",
+	)
+	rootCmd.AddCommand(watchCmd)
+}
+
+// inbox polls dir for link files and downloads them one at a time.
+type inbox struct {
+	dir        string
+	httpClient *http.Client
+	opts       ytdl.DownloadOptions
+	out        io.Writer
+	// seen holds the size and modification time of files at the last poll,
+	// a file is only picked up once it stopped changing.
+	seen map[string]string
+}
+
+// watch polls the folder every interval until ctx is canceled.
+func (in *inbox) watch(ctx context.Context, interval time.Duration) error {
+	for _, sub := range []string{"done", "failed"} {
+		if err := rootpath.CreateDirectoryIfNotExists(filepath.Join(in.dir, sub)); err != nil {
+			return err
+		}
+	}
+	in.seen = make(map[string]string)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := in.poll(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (in *inbox) poll(ctx context.Context) error {
+	entries, err := os.ReadDir(in.dir)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]string)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".txt" && ext != ".url") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		state := fmt.Sprintf("%d %d", info.Size(), info.ModTime().UnixNano())
+		current[entry.Name()] = state
+		// still being written, look again at the next poll
+		if in.seen[entry.Name()] != state {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		in.process(ctx, entry.Name())
+		delete(current, entry.Name())
+	}
+
+	in.seen = current
+	return nil
+}
+
+// process downloads the links of one file and moves it to done/ or failed/.
+// A file interrupted by ctx stays in the inbox and is processed again on the next start.
+func (in *inbox) process(ctx context.Context, name string) {
+	path := filepath.Join(in.dir, name)
+	fmt.Fprintf(in.out, "Processing %s\n", name)
+
+	var log strings.Builder
+	// a video listed twice in the file is only downloaded once
+	client := ytdl.New(ytdl.Options{
+		HTTPClient:     in.httpClient,
+		Log:            io.MultiWriter(in.out, &log),
+		SkipDuplicates: true,
+	})
+
+	lines, err := readInboxFile(path)
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	} else if len(lines) == 0 {
+		errs = append(errs, fmt.Errorf("no links found in %s", name))
+	}
+	// a mistake on any line fails the file before anything is downloaded
+	lineOpts := make([]ytdl.DownloadOptions, len(lines))
+	for i, line := range lines {
+		if lineOpts[i], err = line.applyInbox(in.opts); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		for i, line := range lines {
+			result, events := client.Download(ctx, line.Link, lineOpts[i])
+			for range events {
+			}
+			errs = append(errs, result.Errors...)
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	target := "done"
+	if len(errs) > 0 {
+		target = "failed"
+		fmt.Fprintf(&log, "\nThe following issues occurred during execution:\n")
+		for _, err := range errs {
+			fmt.Fprintf(&log, " - %v\n", err)
+		}
+	}
+
+	dst := inboxTarget(filepath.Join(in.dir, target), name)
+	if err := os.Rename(path, dst); err != nil {
+		fmt.Fprintf(in.out, "ERROR: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(dst+".log", []byte(log.String()), 0644); err != nil {
+		fmt.Fprintf(in.out, "ERROR: %v\n", err)
+	}
+	fmt.Fprintf(in.out, "Moved %s to %s/\n", name, target)
+}
+
+// inboxTarget returns where name goes in dir, prefixed with the time when the name is taken.
+func inboxTarget(dir string, name string) string {
+	dst := filepath.Join(dir, name)
+	if _, err := os.Stat(dst); err == nil {
+		dst = filepath.Join(dir, time.Now().Format("20060102-150405")+"-"+name)
+	}
+	return dst
+}
+
+// applyInbox is apply for a dropped file, whose dst can't leave the --dst directory.
+func (line batchLine) applyInbox(opts ytdl.DownloadOptions) (ytdl.DownloadOptions, error) {
+	for _, o := range line.Overrides {
+		if o.Key == "dst" && !filepath.IsLocal(o.Value) {
+			return opts, fmt.Errorf("batch file line %d: dst %q must be a relative path inside %s", line.Number, o.Value, opts.OutputDir)
+		}
+	}
+	return line.apply(opts)
+}
+
+// readInboxFile reads the links of a dropped file:
+// .url files are Internet Shortcuts, anything else is text with links in it.
+func readInboxFile(path string) ([]batchLine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if !strings.EqualFold(filepath.Ext(path), ".url") {
+		return parseInboxText(file)
+	}
+
+	// [InternetShortcut]
+	// URL=https://www.youtube.com/watch?v=<id>
+	var lines []batchLine
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && strings.EqualFold(key, "URL") && value != "" {
+			lines = append(lines, batchLine{Number: number, Link: value})
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// parseInboxText reads the links of a text file, which may be a batch file
+// as well as notes or a pasted message. Every http(s) link is downloaded with the
+// key=value options of the batch file format found on its line, other words are ignored.
+func parseInboxText(r io.Reader) ([]batchLine, error) {
+	var lines []batchLine
+
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var links []string
+		var overrides []override
+		for _, field := range strings.Fields(text) {
+			if link := strings.Trim(field, "<>()[]\"',;"); strings.HasPrefix(link, "https://") || strings.HasPrefix(link, "http://") {
+				// a sentence may end right after the link
+				links = append(links, strings.TrimRight(link, ".!?"))
+				continue
+			}
+			if key, value, ok := strings.Cut(field, "="); ok && inboxOptions[key] {
+				overrides = append(overrides, override{key, value})
+			}
+		}
+		for _, link := range links {
+			lines = append(lines, batchLine{Number: number, Link: link, Overrides: overrides})
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// inboxOptions are the batch file options parseInboxText picks up.
+var inboxOptions = map[string]bool{
+	"dst": true, "format": true, "template": true, "audio-only": true, "video-only": true, "author": true,
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+)
+
+func TestParseInboxText(t *testing.T) {
+	text := `# a batch file line works as it does with --batch-file
+https://youtu.be/aaaaaaaaaaa dst=music format=audio
+
+Hey, have a look at https://www.youtube.com/watch?v=bbbbbbbbbbb. It's great!
+(also <https://youtu.be/ccccccccccc>, "http://example.com/clip.mp4")
+words=with equals signs aren't options, dst=talks is
+ftp://example.com/clip.mp4 www.youtube.com/watch?v=ddddddddddd
+`
+	lines, err := parseInboxText(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		number    int
+		link      string
+		overrides string
+	}{
+		{2, "https://youtu.be/aaaaaaaaaaa", "dst=music format=audio"},
+		{4, "https://www.youtube.com/watch?v=bbbbbbbbbbb", ""},
+		{5, "https://youtu.be/ccccccccccc", ""},
+		{5, "http://example.com/clip.mp4", ""},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("found %d links, want %d: %+v", len(lines), len(want), lines)
+	}
+	for i, w := range want {
+		line := lines[i]
+		var overrides []string
+		for _, o := range line.Overrides {
+			overrides = append(overrides, o.Key+"="+o.Value)
+		}
+		if line.Number != w.number || line.Link != w.link || strings.Join(overrides, " ") != w.overrides {
+			t.Errorf("link %d = line %d %s %v, want line %d %s %s", i, line.Number, line.Link, line.Overrides, w.number, w.link, w.overrides)
+		}
+	}
+}
+
+func TestReadInboxFile(t *testing.T) {
+	dir := t.TempDir()
+	shortcut := filepath.Join(dir, "video.URL")
+	if err := os.WriteFile(shortcut, []byte("[InternetShortcut]\r\nURL=https://youtu.be/aaaaaaaaaaa\r\nIconIndex=0\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("watch later: https://youtu.be/bbbbbbbbbbb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, link := range map[string]string{shortcut: "https://youtu.be/aaaaaaaaaaa", notes: "https://youtu.be/bbbbbbbbbbb"} {
+		lines, err := readInboxFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 1 || lines[0].Link != link {
+			t.Errorf("readInboxFile(%s) = %+v, want %s", filepath.Base(path), lines, link)
+		}
+	}
+}
+
+func TestApplyInboxDestination(t *testing.T) {
+	root := t.TempDir()
+	opts := ytdl.DownloadOptions{OutputDir: root}
+
+	tests := []struct {
+		dst  string
+		want string
+	}{
+		{"music", filepath.Join(root, "music")},
+		{"music/live", filepath.Join(root, "music", "live")},
+		{"music/../talks", filepath.Join(root, "talks")},
+		{"/tmp/elsewhere", ""},
+		{"../elsewhere", ""},
+		{"../../../etc", ""},
+		{"music/../../elsewhere", ""},
+		{"..", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		line := batchLine{Number: 1, Link: "https://youtu.be/aaaaaaaaaaa", Overrides: []override{{"dst", tt.dst}}}
+		got, err := line.applyInbox(opts)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("dst=%s was accepted as %s", tt.dst, got.OutputDir)
+			}
+			continue
+		}
+		if err != nil || got.OutputDir != tt.want {
+			t.Errorf("dst=%s: OutputDir = %s, %v, want %s", tt.dst, got.OutputDir, err, tt.want)
+		}
+	}
+
+	// a --batch-file is written by the user running the command, it may go anywhere
+	line := batchLine{Number: 1, Overrides: []override{{"dst", "/tmp/elsewhere"}}}
+	if got, err := line.apply(opts); err != nil || got.OutputDir != "/tmp/elsewhere" {
+		t.Errorf("apply: OutputDir = %s, %v, want /tmp/elsewhere", got.OutputDir, err)
+	}
+}
@@ -0,0 +1,298 @@
+package cookies
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// httpOnlyPrefix marks HttpOnly cookies in files exported by browsers,
+// the line is otherwise a regular entry.
+const httpOnlyPrefix string = "#HttpOnly_"
+
+// Cookie is one line of a Netscape cookies.txt file.
+type Cookie struct {
+	Domain string
+	// IncludeSubdomains is the second column, the cookie is sent to subdomains of Domain too.
+	IncludeSubdomains bool
+	Path              string
+	Secure            bool
+	HttpOnly          bool
+	// Expires is zero for session cookies.
+	Expires time.Time
+	Name    string
+	Value   string
+}
+
+func (c *Cookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && c.Expires.Before(now)
+}
+
+func (c *Cookie) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if host != c.Domain && !(c.IncludeSubdomains && strings.HasSuffix(host, "."+c.Domain)) {
+		return false
+	}
+	if c.Secure && u.Scheme != "https" {
+		return false
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return path == c.Path || strings.HasPrefix(path, strings.TrimSuffix(c.Path, "/")+"/")
+}
+
+// ErrorSyntax a line of the cookies file can't be parsed.
+type ErrorSyntax struct {
+	Line    int
+	Message string
+}
+
+func (e *ErrorSyntax) Error() string {
+	return fmt.Sprintf("cookies file line %d: %s", e.Line, e.Message)
+}
+
+// Jar is an http.CookieJar backed by a Netscape cookies.txt file.
+// Unlike net/http/cookiejar it can list its cookies, so they can be written back.
+type Jar struct {
+	mu      sync.Mutex
+	cookies []*Cookie
+}
+
+// Load reads the cookies.txt file at path into a new jar.
+func Load(path string) (*Jar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cookies, err := Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	return &Jar{cookies: cookies}, nil
+}
+
+// Parse reads cookies in the Netscape format:
+// domain, include subdomains, path, secure, expiration (unix seconds), name and value separated by tabs.
+func Parse(r io.Reader) ([]*Cookie, error) {
+	var cookies []*Cookie
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, &ErrorSyntax{lineNo, fmt.Sprintf("expected 7 tab separated fields, got %d", len(fields))}
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, &ErrorSyntax{lineNo, fmt.Sprintf("invalid expiration %q", fields[4])}
+		}
+
+		cookie := &Cookie{
+			Domain:            strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			IncludeSubdomains: strings.EqualFold(fields[1], "TRUE"),
+			Path:              fields[2],
+			Secure:            strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly:          httpOnly,
+			Name:              fields[5],
+			Value:             fields[6],
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+
+	return cookies, scanner.Err()
+}
+
+// SetCookies implements http.CookieJar, it stores what servers send back.
+// Cookies for a domain u doesn't belong to, or for a public suffix, are dropped.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	for _, c := range cookies {
+		domain, includeSubdomains, ok := cookieDomain(u.Hostname(), c.Domain)
+		if !ok {
+			continue
+		}
+		cookie := &Cookie{
+			Domain:            domain,
+			IncludeSubdomains: includeSubdomains,
+			Path:              c.Path,
+			Secure:            c.Secure,
+			HttpOnly:          c.HttpOnly,
+			Name:              c.Name,
+			Value:             c.Value,
+		}
+		if cookie.Path == "" || !strings.HasPrefix(cookie.Path, "/") {
+			cookie.Path = "/"
+		}
+		switch {
+		case c.MaxAge < 0:
+			cookie.Expires = now.Add(-time.Second)
+		case c.MaxAge > 0:
+			cookie.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			cookie.Expires = c.Expires
+		}
+
+		j.set(cookie, now)
+	}
+}
+
+// cookieDomain returns the domain of a cookie host set with the given Domain attribute, following RFC 6265:
+// a server may only set cookies for its own host or a parent domain of it, and never for a public suffix
+// such as com or co.uk. ok is false when the cookie has to be rejected.
+func cookieDomain(host, domain string) (cookieDomain string, includeSubdomains bool, ok bool) {
+	host = strings.ToLower(host)
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if domain == "" {
+		return host, false, true
+	}
+
+	// IP addresses have no parent domains
+	if net.ParseIP(host) != nil {
+		return host, false, domain == host
+	}
+	if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+		// only the host itself, e.g. a site served at a public suffix, may name it
+		return host, false, domain == host
+	}
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return "", false, false
+	}
+	return domain, true, true
+}
+
+// set replaces the cookie with the same domain, path and name, expired cookies are dropped.
+func (j *Jar) set(cookie *Cookie, now time.Time) {
+	for i, existing := range j.cookies {
+		if existing.Domain == cookie.Domain && existing.Path == cookie.Path && existing.Name == cookie.Name {
+			if cookie.expired(now) {
+				j.cookies = append(j.cookies[:i], j.cookies[i+1:]...)
+			} else {
+				j.cookies[i] = cookie
+			}
+			return
+		}
+	}
+	if !cookie.expired(now) {
+		j.cookies = append(j.cookies, cookie)
+	}
+}
+
+// Cookies implements http.CookieJar, it returns the cookies to send with a request to u.
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	var cookies []*http.Cookie
+	for _, cookie := range j.cookies {
+		if cookie.expired(now) || !cookie.matches(u) {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return cookies
+}
+
+// All returns a copy of the cookies that didn't expire yet.
+func (j *Jar) All() []Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	var cookies []Cookie
+	for _, cookie := range j.cookies {
+		if !cookie.expired(now) {
+			cookies = append(cookies, *cookie)
+		}
+	}
+	return cookies
+}
+
+// Write writes the jar in the Netscape format read by Parse.
+func (j *Jar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Netscape HTTP Cookie File")
+	fmt.Fprintln(bw, "# Written by ytdl, edit at your own risk.")
+	fmt.Fprintln(bw)
+
+	for _, cookie := range j.All() {
+		domain := cookie.Domain
+		if cookie.IncludeSubdomains {
+			domain = "." + domain
+		}
+		if cookie.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !cookie.Expires.IsZero() {
+			expires = cookie.Expires.Unix()
+		}
+
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(cookie.IncludeSubdomains), cookie.Path,
+			netscapeBool(cookie.Secure), expires, cookie.Name, cookie.Value)
+	}
+	return bw.Flush()
+}
+
+// Save writes the jar to path, replacing the file only once it's fully written.
+func (j *Jar) Save(path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := j.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	// cookies are credentials, keep them private
+	if err := os.Chmod(file.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,205 @@
+package cookies
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustParseURL(t *testing.T, link string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func cookieNames(cookies []*http.Cookie) string {
+	var names []string
+	for _, cookie := range cookies {
+		names = append(names, cookie.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestParse(t *testing.T) {
+	jar, err := Load(filepath.Join("testdata", "cookies.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	far := time.Unix(4102444800, 0)
+	want := []Cookie{
+		{Domain: "youtube.com", IncludeSubdomains: true, Path: "/", Secure: true, Expires: far, Name: "PREF", Value: "f6=40000000&tz=Europe.Paris"},
+		{Domain: "youtube.com", IncludeSubdomains: true, Path: "/", Secure: true, HttpOnly: true, Expires: far, Name: "__Secure-3PSID", Value: "g.a000abc-def_123"},
+		{Domain: "www.youtube.com", Path: "/feed", Name: "SESSION", Value: "value with spaces"},
+		{Domain: "accounts.google.com", Path: "/", Secure: true, HttpOnly: true, Expires: far, Name: "LSID", Value: "o.youtube.com|s.youtube:xyz"},
+	}
+	got := jar.All()
+	if len(got) != len(want) {
+		t.Fatalf("got %d cookies, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Expires.Equal(want[i].Expires) {
+			t.Errorf("cookie %d expires %v, want %v", i, got[i].Expires, want[i].Expires)
+		}
+		got[i].Expires, want[i].Expires = time.Time{}, time.Time{}
+		if got[i] != want[i] {
+			t.Errorf("cookie %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"youtube.com\tTRUE\t/\tTRUE\t0\tPREF",
+		"youtube.com\tTRUE\t/\tTRUE\tnever\tPREF\tvalue",
+	} {
+		_, err := Parse(strings.NewReader("# header\n" + input + "\n"))
+		var syntax *ErrorSyntax
+		if !errors.As(err, &syntax) || syntax.Line != 2 {
+			t.Errorf("Parse(%q) error = %v, want a syntax error on line 2", input, err)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	jar, err := Load(filepath.Join("testdata", "cookies.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(mustParseURL(t, "https://www.youtube.com/watch"), []*http.Cookie{
+		{Name: "VISITOR_INFO1_LIVE", Value: "abc", Domain: ".youtube.com", Path: "/", MaxAge: 3600, HttpOnly: true, Secure: true},
+		{Name: "YSC", Value: "session"},
+	})
+
+	var first bytes.Buffer
+	if err := jar.Write(&first); err != nil {
+		t.Fatal(err)
+	}
+	cookies, err := Parse(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatalf("Parse can't read what Write wrote: %v\n%s", err, first.String())
+	}
+	// the expired cookie isn't written
+	if len(cookies) != 6 {
+		t.Fatalf("read back %d cookies, want 6:\n%s", len(cookies), first.String())
+	}
+
+	var second bytes.Buffer
+	if err := (&Jar{cookies: cookies}).Write(&second); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("writing the parsed cookies again changed them:\n%s\nvs\n%s", first.String(), second.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := jar.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.All()) != 6 {
+		t.Errorf("loaded %d saved cookies, want 6", len(saved.All()))
+	}
+}
+
+func TestCookiesMatching(t *testing.T) {
+	jar, err := Load(filepath.Join("testdata", "cookies.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://youtube.com/", "PREF,__Secure-3PSID"},
+		{"https://www.youtube.com/watch?v=x", "PREF,__Secure-3PSID"},
+		{"https://WWW.YouTube.com/feed/subscriptions", "PREF,__Secure-3PSID,SESSION"},
+		{"https://www.youtube.com/feed", "PREF,__Secure-3PSID,SESSION"},
+		// /feedback isn't under /feed
+		{"https://www.youtube.com/feedback", "PREF,__Secure-3PSID"},
+		// secure cookies aren't sent over http
+		{"http://www.youtube.com/feed", "SESSION"},
+		// SESSION is for www only, LSID for accounts only
+		{"https://m.youtube.com/feed", "PREF,__Secure-3PSID"},
+		{"https://accounts.google.com/", "LSID"},
+		{"https://mail.accounts.google.com/", ""},
+		{"https://www.google.com/", ""},
+		{"https://notyoutube.com/", ""},
+		{"https://youtube.com.evil.example/", ""},
+	}
+	for _, tt := range tests {
+		if got := cookieNames(jar.Cookies(mustParseURL(t, tt.link))); got != tt.want {
+			t.Errorf("Cookies(%s) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestSetCookiesDomain(t *testing.T) {
+	tests := []struct {
+		name   string
+		link   string
+		domain string
+		// sentTo and notSentTo are checked after the cookie is set, sentTo is empty for rejected cookies
+		sentTo    string
+		notSentTo string
+	}{
+		{"host only", "https://www.youtube.com/", "", "https://www.youtube.com/", "https://m.www.youtube.com/"},
+		{"own host", "https://www.youtube.com/", "www.youtube.com", "https://m.www.youtube.com/", "https://youtube.com/"},
+		{"parent domain", "https://www.youtube.com/", ".youtube.com", "https://music.youtube.com/", "https://google.com/"},
+		{"case and dot", "https://WWW.youtube.com/", ".YouTube.COM", "https://youtube.com/", ""},
+		{"sibling", "https://www.youtube.com/", "music.youtube.com", "", "https://music.youtube.com/"},
+		{"other site", "https://www.youtube.com/", "google.com", "", "https://google.com/"},
+		{"suffix without a dot", "https://notyoutube.com/", "youtube.com", "", "https://youtube.com/"},
+		{"child domain", "https://youtube.com/", "www.youtube.com", "", "https://www.youtube.com/"},
+		{"public suffix", "https://www.youtube.com/", "com", "", "https://google.com/"},
+		{"multi-label public suffix", "https://shop.example.co.uk/", "co.uk", "", "https://other.co.uk/"},
+		{"public suffix host", "https://github.io/", "github.io", "https://github.io/", "https://someone.github.io/"},
+		{"ip address", "http://127.0.0.1/", "127.0.0.1", "http://127.0.0.1/", ""},
+		{"ip address parent", "http://127.0.0.1/", "0.1", "", "http://127.0.0.1/"},
+	}
+	for _, tt := range tests {
+		jar := &Jar{}
+		jar.SetCookies(mustParseURL(t, tt.link), []*http.Cookie{{Name: "C", Value: "v", Domain: tt.domain}})
+
+		if tt.sentTo == "" {
+			if all := jar.All(); len(all) != 0 {
+				t.Errorf("%s: %s setting a cookie for %q was accepted as %+v", tt.name, tt.link, tt.domain, all[0])
+			}
+		} else if got := cookieNames(jar.Cookies(mustParseURL(t, tt.sentTo))); got != "C" {
+			t.Errorf("%s: the cookie isn't sent to %s", tt.name, tt.sentTo)
+		}
+		if tt.notSentTo != "" {
+			if got := cookieNames(jar.Cookies(mustParseURL(t, tt.notSentTo))); got != "" {
+				t.Errorf("%s: the cookie is sent to %s", tt.name, tt.notSentTo)
+			}
+		}
+	}
+}
+
+func TestSetCookiesExpiry(t *testing.T) {
+	jar := &Jar{}
+	u := mustParseURL(t, "https://www.youtube.com/")
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "A", Value: "1"}, {Name: "B", Value: "1", Path: "/watch"}})
+	jar.SetCookies(u, []*http.Cookie{{Name: "A", Value: "2"}})
+	if got := jar.Cookies(u); len(got) != 1 || got[0].Value != "2" {
+		t.Errorf("Cookies = %v, want A=2 replacing A=1", got)
+	}
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "A", MaxAge: -1}, {Name: "B", Path: "/watch", Expires: time.Unix(1, 0)}})
+	if all := jar.All(); len(all) != 0 {
+		t.Errorf("expired cookies are kept: %+v", all)
+	}
+}
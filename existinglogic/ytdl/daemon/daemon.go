@@ -0,0 +1,262 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/queue"
+)
+
+// Operations understood by the daemon.
+const (
+	OpEnqueue = "enqueue"
+	OpList    = "list"
+	OpCancel  = "cancel"
+	OpRetry   = "retry"
+)
+
+// Request is sent by a client as a single JSON line.
+type Request struct {
+	Op  string     `json:"op"`
+	ID  string     `json:"id,omitempty"`
+	Job *queue.Job `json:"job,omitempty"`
+}
+
+// Response is the single JSON line the daemon answers with.
+type Response struct {
+	Jobs  []queue.Job `json:"jobs,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// Runner executes one job, it's downloader.DownloadLink in practice.
+// It must give up once ctx is canceled.
+type Runner func(ctx context.Context, job queue.Job) []error
+
+// Server pulls jobs from the store and runs at most Concurrency of them at a time,
+// while answering enqueue/list/cancel/retry requests on a unix socket.
+type Server struct {
+	Store       *queue.Store
+	SocketPath  string
+	Concurrency int
+	Run         Runner
+	// Log receives what the workers are doing, os.Stdout when nil.
+	Log io.Writer
+
+	wake chan struct{}
+	ctx  context.Context
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// ListenAndServe blocks until the socket listener fails or ctx is canceled.
+// Jobs interrupted by ctx stay running in the store and are queued again on the next start.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if s.Concurrency < 1 {
+		s.Concurrency = 1
+	}
+	s.wake = make(chan struct{}, s.Concurrency)
+	s.ctx = ctx
+	s.cancels = make(map[string]context.CancelFunc)
+
+	// a stale socket is left behind when the previous daemon was killed
+	if conn, err := net.Dial("unix", s.SocketPath); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already listening on %q", s.SocketPath)
+	}
+	os.Remove(s.SocketPath)
+
+	listener, err := net.Listen("unix", s.SocketPath)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	var workers sync.WaitGroup
+	for i := 0; i < s.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			s.work()
+		}()
+	}
+	// pick up whatever survived the last restart
+	s.notify()
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				// let the running jobs clean up their partial files
+				workers.Wait()
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// work runs queued jobs one after the other until the server shuts down.
+func (s *Server) work() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wake:
+		}
+
+		for s.ctx.Err() == nil {
+			job, ok, err := s.Store.Next()
+			if err != nil {
+				fmt.Fprintf(s.log(), "ERROR: %v\n", err)
+				break
+			}
+			if !ok {
+				break
+			}
+
+			fmt.Fprintf(s.log(), "Running job %s: %s\n", job.ID, job.Link)
+			errs := s.runJob(job)
+			if s.ctx.Err() != nil {
+				fmt.Fprintf(s.log(), "Job %s interrupted, it will be resumed on the next start\n", job.ID)
+				break
+			}
+			job, err = s.Store.Finish(job.ID, errs)
+			if err != nil {
+				fmt.Fprintf(s.log(), "ERROR: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(s.log(), "Job %s %s\n", job.ID, job.Status)
+		}
+	}
+}
+
+func (s *Server) log() io.Writer {
+	if s.Log == nil {
+		return os.Stdout
+	}
+	return s.Log
+}
+
+// runJob runs job with a context that OpCancel can cancel.
+func (s *Server) runJob(job queue.Job) []error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	s.mu.Lock()
+	s.cancels[job.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.cancels, job.ID)
+		s.mu.Unlock()
+	}()
+
+	return s.Run(ctx, job)
+}
+
+// notify wakes up idle workers without ever blocking the caller.
+func (s *Server) notify() {
+	for i := 0; i < s.Concurrency; i++ {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		writeResponse(conn, Response{Error: err.Error()})
+		return
+	}
+
+	jobs, err := s.dispatch(req)
+	if err != nil {
+		writeResponse(conn, Response{Error: err.Error()})
+		return
+	}
+	writeResponse(conn, Response{Jobs: jobs})
+}
+
+func (s *Server) dispatch(req Request) ([]queue.Job, error) {
+	switch req.Op {
+	case OpEnqueue:
+		if req.Job == nil || req.Job.Link == "" {
+			return nil, errors.New("enqueue requires a job with a link")
+		}
+		job, err := s.Store.Enqueue(*req.Job)
+		if err != nil {
+			return nil, err
+		}
+		s.notify()
+		return []queue.Job{job}, nil
+	case OpList:
+		return s.Store.List(), nil
+	case OpCancel:
+		job, err := s.Store.Cancel(req.ID)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if cancel, ok := s.cancels[job.ID]; ok {
+			cancel()
+		}
+		s.mu.Unlock()
+		return []queue.Job{job}, nil
+	case OpRetry:
+		job, err := s.Store.Retry(req.ID)
+		if err != nil {
+			return nil, err
+		}
+		s.notify()
+		return []queue.Job{job}, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %q", req.Op)
+	}
+}
+
+func writeResponse(conn net.Conn, resp Response) {
+	w := bufio.NewWriter(conn)
+	json.NewEncoder(w).Encode(resp)
+	w.Flush()
+}
+
+// Call sends a single request to the daemon listening on socketPath.
+func Call(socketPath string, req Request) ([]queue.Job, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("daemon is not running on %q: %w", socketPath, err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	return resp.Jobs, nil
+}
@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/queue"
+)
+
+// waitFor polls the daemon until the job with id has status.
+func waitFor(t *testing.T, socket, id string, status queue.Status) queue.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		jobs, err := Call(socket, Request{Op: OpList})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, job := range jobs {
+			if job.ID == id && job.Status == status {
+				return job
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s never became %s: %+v", id, status, jobs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	store, err := queue.Open(filepath.Join(dir, "jobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "ytdl.sock")
+	started := make(chan string, 10)
+	server := &Server{
+		Store:       store,
+		SocketPath:  socket,
+		Concurrency: 2,
+		Log:         io.Discard,
+		Run: func(ctx context.Context, job queue.Job) []error {
+			started <- job.Link
+			// "block" runs until it's canceled
+			if job.Link == "block" {
+				<-ctx.Done()
+				return []error{ctx.Err()}
+			}
+			return nil
+		},
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- server.ListenAndServe(ctx) }()
+	defer func() {
+		stop()
+		if err := <-served; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// the socket is up once a request goes through
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := Call(socket, Request{Op: OpList}); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	jobs, err := Call(socket, Request{Op: OpEnqueue, Job: &queue.Job{Link: "https://youtu.be/aaaaaaaaaaa", OutputDir: "/music"}})
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("enqueue = %+v, %v", jobs, err)
+	}
+	done := waitFor(t, socket, jobs[0].ID, queue.StatusDone)
+	if done.OutputDir != "/music" || done.Attempts != 1 {
+		t.Errorf("done job = %+v", done)
+	}
+
+	jobs, err = Call(socket, Request{Op: OpEnqueue, Job: &queue.Job{Link: "block"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocked := jobs[0].ID
+	for link := range started {
+		if link == "block" {
+			break
+		}
+	}
+	if _, err := Call(socket, Request{Op: OpCancel, ID: blocked}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, socket, blocked, queue.StatusCanceled)
+	if _, err := Call(socket, Request{Op: OpRetry, ID: blocked}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, socket, blocked, queue.StatusRunning)
+
+	// errors come back over the socket
+	if _, err := Call(socket, Request{Op: OpEnqueue}); err == nil {
+		t.Error("enqueue without a job succeeded")
+	}
+	if _, err := Call(socket, Request{Op: OpCancel, ID: "missing"}); err == nil {
+		t.Error("cancel of a missing job succeeded")
+	}
+	if _, err := Call(socket, Request{Op: "pause"}); err == nil {
+		t.Error("unknown operation succeeded")
+	}
+}
@@ -0,0 +1,83 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictPolicy decides what happens when the file a video is saved to already exists,
+// be it from a previous run or another video of the same name.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps an existing file of the same size as the stream and doesn't download it again.
+	// A file of another size is taken as a stale or broken download and replaced.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing file.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictRename saves the video as "<name> (2).<ext>", "<name> (3).<ext>"...
+	ConflictRename ConflictPolicy = "rename"
+	// ConflictError fails the download.
+	ConflictError ConflictPolicy = "error"
+)
+
+// ErrorFileExists the output file exists and the policy is ConflictError.
+type ErrorFileExists struct {
+	Path string
+}
+
+func (e *ErrorFileExists) Error() string {
+	return fmt.Sprintf("'%s' already exists, pass --on-conflict=skip|overwrite|rename to download anyway", e.Path)
+}
+
+// resolveConflict returns the path to save a stream of size bytes to (0 or less when unknown),
+// or skip when the existing file is kept instead.
+func resolveConflict(path string, size int64, policy ConflictPolicy) (string, bool, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return path, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	switch policy {
+	case ConflictSkip, "":
+		// without a size to compare to, any non empty file is taken as complete
+		if info.Size() == size || (size <= 0 && info.Size() > 0) {
+			return path, true, nil
+		}
+		return path, false, nil
+	case ConflictOverwrite:
+		return path, false, nil
+	case ConflictRename:
+		return freePath(path), false, nil
+	case ConflictError:
+		return "", false, &ErrorFileExists{path}
+	default:
+		return "", false, fmt.Errorf("unknown conflict policy %q, expected skip, overwrite, rename or error", policy)
+	}
+}
+
+// Output resolves a conflict on a file whose size isn't known before it's written,
+// like the output of a post-processing step. It's a postprocess.Item.Output.
+func (policy ConflictPolicy) Output(path string) (string, bool, error) {
+	return resolveConflict(path, -1, policy)
+}
+
+// freePath appends " (2)", " (3)"... to the file name until it doesn't exist.
+func freePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		if _, err := os.Stat(candidate); errors.Is(err, fs.ErrNotExist) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+func TestResolveConflict(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(existing, []byte("12345"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "video (2).mp4"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "other.mp4")
+
+	tests := []struct {
+		name   string
+		path   string
+		size   int64
+		policy ConflictPolicy
+		want   string
+		skip   bool
+		err    bool
+	}{
+		{"missing file", missing, 5, ConflictError, missing, false, false},
+		{"skip complete", existing, 5, ConflictSkip, existing, true, false},
+		{"default is skip", existing, 5, "", existing, true, false},
+		{"skip unknown size", existing, -1, ConflictSkip, existing, true, false},
+		{"skip replaces a stale file", existing, 9, ConflictSkip, existing, false, false},
+		{"overwrite", existing, 5, ConflictOverwrite, existing, false, false},
+		{"rename", existing, 5, ConflictRename, filepath.Join(dir, "video (3).mp4"), false, false},
+		{"error", existing, 5, ConflictError, "", false, true},
+		{"unknown policy", existing, 5, "ask", "", false, true},
+	}
+	for _, tt := range tests {
+		path, skip, err := resolveConflict(tt.path, tt.size, tt.policy)
+		if path != tt.want || skip != tt.skip || (err != nil) != tt.err {
+			t.Errorf("%s: resolveConflict = %q, %v, %v, want %q, %v, error %v", tt.name, path, skip, err, tt.want, tt.skip, tt.err)
+		}
+	}
+
+	var exists *ErrorFileExists
+	if _, _, err := resolveConflict(existing, 5, ConflictError); !errors.As(err, &exists) || exists.Path != existing {
+		t.Errorf("error = %v, want ErrorFileExists for %s", err, existing)
+	}
+}
+
+// openCounter is an extractor streaming content, it counts the streams opened.
+type openCounter struct {
+	extractor.Generic
+	content string
+	opened  int
+}
+
+func (o *openCounter) Open(ctx context.Context, media *extractor.Media, format *models.FormatInfo) (io.ReadCloser, int64, error) {
+	o.opened++
+	return io.NopCloser(strings.NewReader(o.content)), int64(len(o.content)), nil
+}
+
+func TestSaveVideoConflict(t *testing.T) {
+	media := &extractor.Media{VideoInfo: models.VideoInfo{ID: "aaaaaaaaaaa", Title: "video"}}
+	format := &models.FormatInfo{ContentLength: 9, Extension: ".mp4"}
+
+	tests := []struct {
+		name     string
+		existing string
+		policy   ConflictPolicy
+		opened   int
+		content  string
+		skipped  bool
+		err      bool
+	}{
+		// the stream of a kept file isn't even opened
+		{"skip complete", "old video", ConflictSkip, 0, "old video", true, false},
+		{"error", "old video", ConflictError, 0, "old video", false, true},
+		{"skip stale", "old", ConflictSkip, 1, "new video", false, false},
+		{"overwrite", "old video", ConflictOverwrite, 1, "new video", false, false},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "video.mp4")
+		if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ex := &openCounter{content: "new video"}
+		opts := &Options{OutputDir: dir, OnConflict: tt.policy, Log: io.Discard}
+
+		saved, skipped, err := saveVideo(context.Background(), ex, "https://example.com/video.mp4", media, format, 0, path, opts)
+		if (err != nil) != tt.err || skipped != tt.skipped || ex.opened != tt.opened {
+			t.Errorf("%s: saveVideo = %v, skipped %v, opened %d times, want error %v, skipped %v, opened %d times",
+				tt.name, err, skipped, ex.opened, tt.err, tt.skipped, tt.opened)
+		}
+		if !tt.err && saved != path {
+			t.Errorf("%s: saved to %s, want %s", tt.name, saved, path)
+		}
+		if content, _ := os.ReadFile(path); string(content) != tt.content {
+			t.Errorf("%s: file holds %q, want %q", tt.name, content, tt.content)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("%s: %d files in the output directory, want 1", tt.name, len(entries))
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package downloader
+
+import "sync"
+
+// Dedup remembers the videos handled across DownloadLink calls sharing it,
+// so a video listed twice (or found in two playlists) is fetched once.
+// Videos are told apart by the canonical url their extractor gives them,
+// e.g. youtu.be and watch?v= links of a video share https://www.youtube.com/watch?v=<id>.
+// A nil Dedup remembers nothing.
+type Dedup struct {
+	mu    sync.Mutex
+	paths map[string]string
+}
+
+func NewDedup() *Dedup {
+	return &Dedup{paths: make(map[string]string)}
+}
+
+// Has reports whether the video was already claimed, and where it's saved.
+func (d *Dedup) Has(url string) (string, bool) {
+	if d == nil || url == "" {
+		return "", false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path, ok := d.paths[url]
+	return path, ok
+}
+
+// claim marks the video as being saved to path.
+// It returns false with the path of the first claim when the video was already claimed.
+func (d *Dedup) claim(url string, path string) (string, bool) {
+	if d == nil || url == "" {
+		return path, true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if existing, ok := d.paths[url]; ok {
+		return existing, false
+	}
+	d.paths[url] = path
+	return path, true
+}
+
+// release forgets a claim whose download failed, so a later duplicate gets another try.
+func (d *Dedup) release(url string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.paths, url)
+}
+
+// update records the path a claimed video was finally saved to.
+func (d *Dedup) update(url string, path string) {
+	if d == nil || url == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.paths[url] = path
+}
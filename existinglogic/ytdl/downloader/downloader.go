@@ -1,15 +1,19 @@
 package downloader
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
-	"ytdl/parser"
-	"ytdl/rootpath"
+	"time"
 
-	"github.com/kkdai/youtube/v2"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/progress"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/rootpath"
 )
 
 // TODO: meant to download multiple videos CONCURRENTLY
@@ -19,16 +23,19 @@ func DownloadVideos(links []string, outputDir string) []error {
 
 // There are cases where the link opens is already in a playlist
 // In that scenario we can either both extract a playlist, or just the video in the playlist
-func DownloadLink(link string, outputDir string, extractVideoOnly bool, includeAuthor bool) []error {
-	videoLinkParsed, err := parser.ParseVideoUrl(link)
+func DownloadLink(ctx context.Context, link string, opts Options) []error {
+	ex, err := opts.registry().Find(link)
 	if err != nil {
+		opts.emit(Event{Type: EventError, Link: link, Error: err.Error()})
 		return []error{err}
 	}
 
 	// early return check
-	if len(videoLinkParsed.Playlist) == 0 {
-		fmt.Println("video link does not contain a playlist id. force downloading a video...")
-		err = DownloadVideo(link, outputDir, includeAuthor)
+	if !ex.IsPlaylist(link) {
+		// a playlist isn't a download of its own, its entries are queued once they are listed
+		opts.emit(Event{Type: EventQueued, Link: link})
+		fmt.Fprintln(opts.log(), "video link does not contain a playlist id. force downloading a video...")
+		err = DownloadVideo(ctx, link, opts)
 		if err != nil {
 			return []error{err}
 		}
@@ -36,9 +43,10 @@ func DownloadLink(link string, outputDir string, extractVideoOnly bool, includeA
 	}
 
 	// video only option
-	if extractVideoOnly {
-		fmt.Println("extracting only the video")
-		err = DownloadVideo(link, outputDir, includeAuthor)
+	if opts.VideoOnly {
+		opts.emit(Event{Type: EventQueued, Link: link})
+		fmt.Fprintln(opts.log(), "extracting only the video")
+		err = DownloadVideo(ctx, link, opts)
 		if err != nil {
 			return []error{err}
 		}
@@ -46,14 +54,8 @@ func DownloadLink(link string, outputDir string, extractVideoOnly bool, includeA
 	}
 
 	// playlist option
-	fmt.Println("extracting entire playlist")
-	playlistLink, err := parser.ConvertVideoLinkToPlaylistLink(link)
-	if err != nil {
-		return []error{err}
-	}
-	//fmt.Println(fmt.Sprintf("playlist link: '%s'", playlistLink))
-
-	errs := DownloadPlaylist(playlistLink, outputDir, includeAuthor)
+	fmt.Fprintln(opts.log(), "extracting entire playlist")
+	errs := DownloadPlaylist(ctx, link, opts)
 	if len(errs) > 0 {
 		return errs
 	}
@@ -61,186 +63,427 @@ func DownloadLink(link string, outputDir string, extractVideoOnly bool, includeA
 }
 
 // single execution of a video download process
-func DownloadVideo(link string, outputDir string, includeAuthor bool) error {
-
-	videoLinkParsed, err := parser.ParseVideoUrl(link)
-	if err != nil {
+func DownloadVideo(ctx context.Context, link string, opts Options) error {
+	fail := func(err error) error {
+		opts.emit(Event{Type: EventError, Link: link, Error: err.Error()})
 		return err
 	}
 
-	client := youtube.Client{}
-
-	video, err := client.GetVideo(videoLinkParsed.VideoId)
+	ex, err := opts.registry().Find(link)
 	if err != nil {
-		return err
+		return fail(err)
 	}
 
-	formats := video.Formats.WithAudioChannels()
-
-	// TODO: maybe a better way to utilize the stream?
-	stream, _, err := client.GetStream(video, &formats[0])
+	opts.progress().Stage(link, "extracting")
+	media, err := ex.Extract(ctx, link)
 	if err != nil {
-		return err
+		return fail(err)
+	}
+	if path, ok := opts.Dedup.Has(media.Url); ok {
+		fmt.Fprintf(opts.log(), "\tAlready downloaded to '%s', skipping\n", path)
+		opts.emit(Event{Type: EventSkipped, Link: link, VideoID: media.ID, Title: media.Title, Path: path})
+		return nil
 	}
 
-	// defer means handle it after function executes
-	defer stream.Close()
-
-	videoName, err := createVideoName(video, true, includeAuthor)
-
-	fmt.Printf("\tDownloading %s...", videoName)
-	outputPath := filepath.Join(outputDir, videoName)
-	file, err := os.Create(outputPath)
-
+	format, err := selectFormat(media, &opts)
 	if err != nil {
-		return err
+		return fail(err)
 	}
-	defer file.Close()
-
-	_, err = io.Copy(file, stream)
 
+	videoName, err := createVideoName(opts.OutputDir, media, format, 0, &opts)
 	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Fprintf(opts.log(), "\tDownloading %s...\n", videoName)
+	outputPath := filepath.Join(opts.OutputDir, videoName)
+	_, skipped, err := saveVideo(ctx, ex, link, media, format, 0, outputPath, &opts)
+	if err != nil || skipped {
 		return err
 	}
 
-	fmt.Printf("\tDownloaded %s successfully", videoName)
+	fmt.Fprintf(opts.log(), "\tDownloaded %s successfully\n", videoName)
 	return nil
 }
 
+// fetches metadata and the available formats of a single video without downloading it
+func GetMedia(ctx context.Context, link string, opts Options) (*extractor.Media, error) {
+	ex, err := opts.registry().Find(link)
+	if err != nil {
+		return nil, err
+	}
+	return ex.Extract(ctx, link)
+}
+
 // single execution of a playlist download
-func DownloadPlaylist(link string, outputDir string, includeAuthor bool) []error {
+func DownloadPlaylist(ctx context.Context, link string, opts Options) []error {
 
-	client := youtube.Client{}
-	playlistLinkParsed, err := parser.ParsePlaylistUrl(link)
+	ex, err := opts.registry().Find(link)
 	if err != nil {
 		return []error{err}
 	}
 
-	playlist, err := client.GetPlaylist(playlistLinkParsed.PlaylistId)
+	playlist, err := ex.ExtractPlaylist(ctx, link)
 
 	if err != nil {
 		return []error{err}
 	}
 
 	// Enumerate Playlist videos
-	playlistFolderName, err := createPlaylistName(playlist, includeAuthor)
-	playlistPath := filepath.Join(outputDir, playlistFolderName)
+	playlistFolderName, err := createPlaylistName(playlist, &opts)
+	if err != nil {
+		return []error{err}
+	}
+	playlistPath := filepath.Join(opts.OutputDir, playlistFolderName)
 	err = rootpath.CreateDirectoryIfNotExists(playlistPath)
 
 	if err != nil {
-		panic(err)
+		return []error{err}
 	}
 
-	header := fmt.Sprintf("Playlist: %s", playlistFolderName)
-	fmt.Println(header)
-	fmt.Println(strings.Repeat("=", len(header)) + "\n")
+	if opts.WritePlaylistMetafiles {
+		err = writePlaylistSidecar(playlist, playlistPath)
+		if err != nil {
+			return []error{err}
+		}
+	}
 
-	fmt.Printf("Downloading Playlist to: %s...\n\n", playlistPath)
+	header := fmt.Sprintf("Playlist: %s", playlistFolderName)
+	fmt.Fprintln(opts.log(), header)
+	fmt.Fprintln(opts.log(), strings.Repeat("=", len(header))+"\n")
+
+	fmt.Fprintf(opts.log(), "Downloading Playlist to: %s...\n\n", playlistPath)
+
+	for _, entry := range playlist.Entries {
+		opts.emit(Event{
+			Type:     EventQueued,
+			Link:     entry.Url,
+			VideoID:  entry.ID,
+			Title:    entry.Title,
+			Duration: entry.Duration,
+		})
+	}
 
 	var errors []error
-	for index, entry := range playlist.Videos {
+	downloaded := 0
+	for _, entry := range playlist.Entries {
+		// stop picking up new entries once interrupted
+		if ctx.Err() != nil {
+			errors = append(errors, ctx.Err())
+			break
+		}
 
 		// TODO: make this multithreaded
-		err = downloadVideoForPlaylist(&client, index, entry, playlistPath, includeAuthor)
+		// unavailable entries (private, members-only...) are skipped and reported below
+		_, _, err = downloadVideoForPlaylist(ctx, ex, entry, playlistPath, &opts)
 		if err != nil {
-			errors = append(errors, err)
+			errors = append(errors, fmt.Errorf("(%d) %s: %w", entry.PlaylistIndex, entry.Title, err))
+			continue
 		}
+		downloaded++
 	}
 
-	fmt.Printf("\nFinished Playlist download to %s\n\n", playlistPath)
+	fmt.Fprintf(opts.log(), "\nFinished Playlist download to %s\n", playlistPath)
 	if len(errors) > 0 {
+		fmt.Fprintf(opts.log(), "Downloaded %d of %d entries, issues:\n", downloaded, len(playlist.Entries))
+		for _, err := range errors {
+			fmt.Fprintf(opts.log(), "\t%v\n", err)
+		}
+		fmt.Fprintln(opts.log())
 		return errors
 	}
+	fmt.Fprintln(opts.log())
 
 	return []error{}
 }
 
-// This is split into its own separate function for robustness
-func downloadVideoForPlaylist(client *youtube.Client,
-	index int,
-	entry *youtube.PlaylistEntry,
-	playlistPath string, includeAuthor bool) error {
+// This is split into its own separate function for robustness.
+// It returns the path the entry was saved to and the metadata its name was made from,
+// which may differ from the playlist listing.
+func downloadVideoForPlaylist(ctx context.Context,
+	ex extractor.Extractor,
+	entry models.VideoInfo,
+	playlistPath string, opts *Options) (string, models.VideoInfo, error) {
+
+	displayIndex := entry.PlaylistIndex
+	fail := func(err error) (string, models.VideoInfo, error) {
+		printError(opts.log(), err, displayIndex, true)
+		opts.emit(Event{Type: EventError, Link: entry.Url, VideoID: entry.ID, Title: entry.Title, Error: err.Error()})
+		return "", entry, err
+	}
 
-	displayIndex := index + 1
+	if path, ok := opts.Dedup.Has(entry.Url); ok {
+		fmt.Fprintf(opts.log(), "\t(%d) Already downloaded to '%s', skipping\n", displayIndex, path)
+		opts.emit(Event{Type: EventSkipped, Link: entry.Url, VideoID: entry.ID, Title: entry.Title, Path: path})
+		return path, entry, nil
+	}
 
-	fmt.Printf("\t(%d) Accessing video for entry: %s - %s\n", displayIndex, entry.Title, entry.Author)
-	video, err := client.VideoFromPlaylistEntry(entry)
+	fmt.Fprintf(opts.log(), "\t(%d) Accessing video for entry: %s - %s\n", displayIndex, entry.Title, entry.Author)
+	opts.progress().Stage(entry.Url, "extracting")
+	media, err := ex.Extract(ctx, entry.Url)
 	if err != nil {
-		printError(err, displayIndex, true)
-		return err
+		return fail(err)
 	}
+	fmt.Fprintf(opts.log(), "\t(%d) Video accessed for entry: %s - %s\n", displayIndex, entry.Title, entry.Author)
 
-	format := &video.Formats[0]
-	stream, _, err := client.GetStream(video, format)
-
+	format, err := selectFormat(media, opts)
 	if err != nil {
-		printError(err, displayIndex, true)
-		return err
+		return fail(err)
 	}
-	fmt.Printf("\t(%d) Video accessed for entry: %s - %s\n", displayIndex, entry.Title, entry.Author)
 
-	fileName, err := createVideoName(video, true, includeAuthor)
+	fileName, err := createVideoName(playlistPath, media, format, displayIndex, opts)
 	if err != nil {
-		printError(err, displayIndex, true)
-		return err
+		return fail(err)
 	}
 
+	fmt.Fprintf(opts.log(), "\t(%d) Downloading '%s'\n", displayIndex, fileName)
 	videoFilePath := filepath.Join(playlistPath, fileName)
-	file, err := os.Create(videoFilePath)
+	videoFilePath, skipped, err := saveVideo(ctx, ex, entry.Url, media, format, displayIndex, videoFilePath, opts)
 	if err != nil {
-		return err
+		printError(opts.log(), err, displayIndex, true)
+		return "", entry, err
+	}
+	if skipped {
+		return videoFilePath, media.VideoInfo, nil
+	}
+
+	fmt.Fprintf(opts.log(), "\t(%d) Downloaded '%s'\n", displayIndex, fileName)
+
+	return videoFilePath, media.VideoInfo, nil
+}
+
+// saveVideo streams format of media into outputPath,
+// reporting progress through opts and writing the requested sidecars.
+// An existing outputPath is handled by opts.OnConflict, the path actually used is returned
+// together with whether the download was skipped.
+// The partial file is removed when the download fails or ctx is canceled.
+func saveVideo(ctx context.Context,
+	ex extractor.Extractor,
+	link string,
+	media *extractor.Media,
+	format *models.FormatInfo,
+	index int,
+	outputPath string, opts *Options) (string, bool, error) {
+
+	event := Event{
+		Link:     link,
+		VideoID:  media.ID,
+		Title:    media.Title,
+		Path:     outputPath,
+		Duration: media.Duration,
+	}
+	start := time.Now()
+
+	if path, ok := opts.Dedup.claim(media.Url, outputPath); !ok {
+		fmt.Fprintf(opts.log(), "\tAlready downloaded to '%s', skipping\n", path)
+		event.Type = EventSkipped
+		event.Path = path
+		opts.emit(event)
+		return path, true, nil
 	}
 
-	fmt.Printf("\t(%d) Downloading '%s'\n", displayIndex, fileName)
-	defer file.Close()
-	_, err = io.Copy(file, stream)
+	fail := func(err error) (string, bool, error) {
+		opts.Dedup.release(media.Url)
+		event.Type = EventError
+		event.Error = err.Error()
+		opts.emit(event)
+		return "", false, err
+	}
+
+	// the stream isn't opened for a file that is kept, the size it will have is the one of the format
+	outputPath, skip, err := resolveConflict(outputPath, format.ContentLength, opts.OnConflict)
 	if err != nil {
-		os.Remove(videoFilePath)
-		printError(err, displayIndex, true)
-		return err
+		return fail(err)
+	}
+	opts.Dedup.update(media.Url, outputPath)
+	event.Path = outputPath
+	if skip {
+		fmt.Fprintf(opts.log(), "\t'%s' already exists, skipping\n", outputPath)
+		event.Type = EventSkipped
+		event.Total = format.ContentLength
+		opts.emit(event)
+		return outputPath, true, nil
 	}
 
-	fmt.Printf("\t(%d) Downloaded '%s'\n", displayIndex, fileName)
+	stream, size, err := ex.Open(ctx, media, format)
+	if err != nil {
+		return fail(err)
+	}
+	// defer means handle it after function executes
+	defer stream.Close()
 
-	return nil
+	event.Type = EventMetadata
+	event.Total = size
+	opts.emit(event)
+
+	progressEvent := event
+	progressEvent.Type = EventProgress
+	reader := &progress.Reader{
+		Reader:   contextReader{Reader: stream, ctx: ctx},
+		Reporter: eventReporter{event: &progressEvent, opts: opts},
+		ID:       link,
+		Interval: progress.ReportInterval,
+	}
+	if err := writeAtomic(outputPath, reader, size, format.ContentLength); err != nil {
+		event.Bytes = reader.N()
+		return fail(err)
+	}
+
+	event.Bytes = reader.N()
+	if outputPath, err = postProcess(ctx, link, media, format, index, outputPath, opts); err != nil {
+		return fail(err)
+	}
+	opts.Dedup.update(media.Url, outputPath)
+	event.Path = outputPath
+	if err := writeSidecars(media, format, index, outputPath, opts); err != nil {
+		return fail(err)
+	}
+
+	event.Type = EventDone
+	event.Elapsed = time.Since(start).Seconds()
+	opts.emit(event)
+	return outputPath, false, nil
 }
 
-func createPlaylistName(pl *youtube.Playlist, includeAuthor bool) (string, error) {
-	plName := pl.Title
+// selectFormat picks the stream to download according to opts.Format and opts.AudioOnly
+func selectFormat(media *extractor.Media, opts *Options) (*models.FormatInfo, error) {
+	formats := filterFormats(media.Formats, func(f models.FormatInfo) bool {
+		return f.AudioChannels > 0
+	})
+	// not every extractor knows about audio channels
+	if len(formats) == 0 {
+		formats = media.Formats
+	}
+
+	if opts.AudioOnly {
+		formats = filterFormats(formats, func(f models.FormatInfo) bool {
+			return strings.Contains(f.MimeType, "audio/mp4")
+		})
+	}
+
+	if opts.Format != "" {
+		if itag, err := strconv.Atoi(opts.Format); err == nil {
+			formats = filterFormats(media.Formats, func(f models.FormatInfo) bool {
+				return f.Itag == itag
+			})
+		} else {
+			formats = filterFormats(formats, func(f models.FormatInfo) bool {
+				return f.QualityLabel == opts.Format || f.Quality == opts.Format
+			})
+		}
+	}
+
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("no format matching '%s' found for video '%s'", opts.Format, media.Title)
+	}
 
-	if includeAuthor && pl.Author != "" {
-		plName = plName + " - " + pl.Author
+	// best audio first when nothing else was asked for
+	if opts.AudioOnly && opts.Format == "" {
+		sort.SliceStable(formats, func(i, j int) bool {
+			return formats[i].Bitrate > formats[j].Bitrate
+		})
 	}
 
-	plName = strings.Replace(plName, "/", "", -1)
-	plName = strings.Replace(plName, "\\", "", -1)
+	return &formats[0], nil
+}
 
-	plName = rootpath.RemoveInvalidFileNameChars(plName)
-	// sanitize folder name??
-	return plName, nil
+func filterFormats(formats []models.FormatInfo, keep func(models.FormatInfo) bool) []models.FormatInfo {
+	var result []models.FormatInfo
+	for _, format := range formats {
+		if keep(format) {
+			result = append(result, format)
+		}
+	}
+	return result
 }
 
-// Creates the file name for the video
-func createVideoName(vid *youtube.Video, includeExt bool, includeAuthor bool) (string, error) {
-	videoStr := vid.Title
+func createPlaylistName(pl *extractor.Playlist, opts *Options) (string, error) {
+	build := func(title, author string) string {
+		plName := title
 
-	if includeAuthor && vid.Author != "" {
-		videoStr = videoStr + " - " + vid.Author
+		if opts.IncludeAuthor && author != "" {
+			plName = plName + " - " + author
+		}
+
+		plName = strings.Replace(plName, "/", "", -1)
+		plName = strings.Replace(plName, "\\", "", -1)
+
+		plName = rootpath.SanitizeFilename(plName, opts.FilenameProfile)
+		// sanitize folder name??
+		return plName
 	}
 
-	if includeExt {
-		videoStr = videoStr + ".mp4"
+	budget := rootpath.FolderBudget(opts.OutputDir, opts.FilenameProfile)
+	return fitName(build, pl.Title, pl.Author, budget, opts.FilenameProfile), nil
+}
+
+// Creates the file name for the video
+func createVideoName(dir string, media *extractor.Media, format *models.FormatInfo, index int, opts *Options) (string, error) {
+	// extractors set the extension from the mime type when they know it
+	extension := format.Extension
+	if extension == "" && opts.AudioOnly {
+		extension = ".m4a"
+	} else if extension == "" {
+		extension = ".mp4"
 	}
 
-	videoStr = strings.Replace(videoStr, "/", "", -1)
-	videoStr = strings.Replace(videoStr, "\\", "", -1)
+	return videoFileName(dir, &media.VideoInfo, extension, index, opts), nil
+}
 
-	videoStr = rootpath.RemoveInvalidFileNameChars(videoStr)
-	// sanitize folder name??
-	return videoStr, nil
+// videoFileName names the file of a video saved in dir from its metadata,
+// it's also used to rename files without fetching the video again.
+// The name is shortened so the full path, while downloading too, fits the filesystem limits.
+func videoFileName(dir string, video *models.VideoInfo, extension string, index int, opts *Options) string {
+	build := func(title, author string) string {
+		vid := *video
+		vid.Title = title
+		vid.Author = author
+		videoStr := vid.Title
+
+		if opts.Template != "" {
+			videoStr = renderTemplate(opts.Template, &vid, index)
+		} else if opts.IncludeAuthor && vid.Author != "" {
+			videoStr = videoStr + " - " + vid.Author
+		}
+
+		videoStr = videoStr + extension
+
+		videoStr = strings.Replace(videoStr, "/", "", -1)
+		videoStr = strings.Replace(videoStr, "\\", "", -1)
+
+		videoStr = rootpath.SanitizeFilename(videoStr, opts.FilenameProfile)
+		// sanitize folder name??
+		return videoStr
+	}
+
+	budget := rootpath.NameBudget(dir, rootpath.PartSuffix, opts.FilenameProfile)
+	return fitName(build, video.Title, video.Author, budget, opts.FilenameProfile)
+}
+
+// fitName builds a name and shortens it until it's at most budget long:
+// the title is shortened first, then the author, ids and indices are kept intact.
+// When even that isn't enough the name is cut, keeping its extension.
+func fitName(build func(title, author string) string, title, author string, budget int, profile rootpath.Profile) string {
+	length := profile.Limits().Length
+	name := build(title, author)
+
+	for _, field := range []*string{&title, &author} {
+		runes := []rune(*field)
+		for length(name) > budget && len(runes) > 0 {
+			runes = runes[:len(runes)-1]
+			*field = strings.TrimSpace(string(runes))
+			name = build(title, author)
+		}
+	}
+
+	if length(name) > budget {
+		ext := filepath.Ext(name)
+		name = rootpath.Truncate(strings.TrimSuffix(name, ext), budget-length(ext), profile) + ext
+	}
+	return name
 }
 
-func printError(err error, index int, indent bool) {
+func printError(w io.Writer, err error, index int, indent bool) {
 	var errMessage strings.Builder
 
 	if indent {
@@ -254,5 +497,5 @@ func printError(err error, index int, indent bool) {
 	errMessage.WriteString("ERROR: ")
 	errMessage.WriteString(err.Error())
 
-	fmt.Println(errMessage.String())
+	fmt.Fprintln(w, errMessage.String())
 }
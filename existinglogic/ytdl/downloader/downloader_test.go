@@ -0,0 +1,55 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/testserver"
+)
+
+func TestDownloadLinkEvents(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	var ids []string
+	for _, id := range []string{"aaaaaaaaaaa", "bbbbbbbbbbb"} {
+		srv.AddVideo(testserver.Video{ID: id, Title: "Video " + id[:1]})
+		ids = append(ids, id)
+	}
+	srv.AddPlaylist(testserver.Playlist{ID: "PLtest0123456789", Title: "Playlist", VideoIDs: ids})
+
+	tests := []struct {
+		name  string
+		link  string
+		tasks int
+	}{
+		{"video", "https://youtu.be/aaaaaaaaaaa", 1},
+		{"playlist", "https://www.youtube.com/playlist?list=PLtest0123456789", 2},
+	}
+	for _, tt := range tests {
+		// the events of every task, by link
+		events := map[string][]EventType{}
+		errs := DownloadLink(context.Background(), tt.link, Options{
+			OutputDir: t.TempDir(),
+			Log:       io.Discard,
+			Registry:  extractor.NewDefaultRegistry(srv.Client()),
+			OnEvent: func(event Event) {
+				events[event.Link] = append(events[event.Link], event.Type)
+			},
+		})
+		if len(errs) > 0 {
+			t.Fatalf("%s: DownloadLink: %v", tt.name, errs)
+		}
+
+		if len(events) != tt.tasks {
+			t.Errorf("%s: events for %d links, want %d: %v", tt.name, len(events), tt.tasks, events)
+		}
+		// every queued task finishes
+		for link, types := range events {
+			if types[0] != EventQueued || types[len(types)-1] != EventDone {
+				t.Errorf("%s: events of %s = %v, want queued to done", tt.name, link, types)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/rootpath"
+)
+
+// ErrorSizeMismatch the stream ended before or after the size announced for it,
+// the download is discarded.
+type ErrorSizeMismatch struct {
+	Path     string
+	Expected int64
+	Got      int64
+}
+
+func (e *ErrorSizeMismatch) Error() string {
+	return fmt.Sprintf("'%s': got %d bytes, expected %d", e.Path, e.Got, e.Expected)
+}
+
+// writeAtomic writes r to path+".part" in the destination directory and only renames it
+// to path once it's synced to disk and has the expected size, so path never holds a partial file.
+// Sizes of 0 or less are unknown and not checked. The .part file is removed on failure.
+func writeAtomic(path string, r io.Reader, sizes ...int64) error {
+	partPath := path + rootpath.PartSuffix
+	file, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(file, r)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = checkSize(path, written, sizes...)
+	}
+	if err == nil {
+		err = os.Rename(partPath, path)
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return err
+	}
+
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+func checkSize(path string, written int64, sizes ...int64) error {
+	for _, size := range sizes {
+		if size > 0 && written != size {
+			return &ErrorSizeMismatch{Path: path, Expected: size, Got: written}
+		}
+	}
+	return nil
+}
+
+// syncDir persists the rename, not every OS lets directories be synced so errors are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,62 @@
+package downloader
+
+import (
+	"context"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+// FetchInfo follows the same video/playlist rules as DownloadLink but only fetches metadata.
+// Exactly one of the returned infos is set when err is nil.
+func FetchInfo(ctx context.Context, link string, opts Options) (*models.VideoInfo, *models.PlaylistInfo, error) {
+	ex, err := opts.registry().Find(link)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !ex.IsPlaylist(link) || opts.VideoOnly {
+		media, err := ex.Extract(ctx, link)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &media.VideoInfo, nil, nil
+	}
+
+	info, err := FetchPlaylistInfo(ctx, link, opts)
+	return nil, info, err
+}
+
+// FetchPlaylistInfo fetches the metadata of the playlist and of every entry in it.
+// Entries that can't be fetched are kept with their Error set.
+func FetchPlaylistInfo(ctx context.Context, link string, opts Options) (*models.PlaylistInfo, error) {
+	ex, err := opts.registry().Find(link)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist, err := ex.ExtractPlaylist(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	info := playlist.PlaylistInfo
+	info.Entries = []models.VideoInfo{}
+	for _, entry := range playlist.Entries {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		media, err := ex.Extract(ctx, entry.Url)
+		if err != nil {
+			entry.Error = err.Error()
+			info.Entries = append(info.Entries, entry)
+			continue
+		}
+
+		entryInfo := media.VideoInfo
+		entryInfo.PlaylistIndex = entry.PlaylistIndex
+		info.Entries = append(info.Entries, entryInfo)
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,159 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/postprocess"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/progress"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/rootpath"
+)
+
+// Options controls how a link is downloaded.
+type Options struct {
+	OutputDir string
+	// VideoOnly downloads only the linked video even if the link points into a playlist.
+	VideoOnly     bool
+	IncludeAuthor bool
+	// Format picks the stream: an itag ("18"), a quality label ("720p") or a quality ("medium").
+	// When empty the first format with audio channels is used.
+	Format string
+	// AudioOnly downloads the best audio only stream instead of a video.
+	AudioOnly bool
+	// Template overrides the file name, see renderTemplate for the supported fields.
+	Template string
+	// WriteInfoJSON writes <name>.info.json with the video metadata and the chosen format.
+	WriteInfoJSON bool
+	// WriteDescription writes the video description to <name>.description.
+	WriteDescription bool
+	// WritePlaylistMetafiles writes playlist.info.json into the playlist folder.
+	WritePlaylistMetafiles bool
+	// OnEvent is called for every queued, metadata, progress, done, skipped and error event.
+	OnEvent func(Event)
+	// Log receives the human readable output, os.Stdout when nil.
+	Log io.Writer
+	// Progress is told about the stages and bytes of every download, nothing is reported when nil.
+	Progress progress.Reporter
+	// Registry resolves links to extractors, extractor.Default when nil.
+	Registry *extractor.Registry
+	// Dedup skips videos already handled by another call sharing it, nothing is skipped when nil.
+	Dedup *Dedup
+	// OnConflict handles output files that already exist, ConflictSkip when empty.
+	OnConflict ConflictPolicy
+	// FilenameProfile restricts file and folder names, the host OS rules apply when empty.
+	FilenameProfile rootpath.Profile
+	// PostProcess runs on every saved file before its sidecars are written next to where it ends up.
+	// Files skipped as already there are left alone.
+	PostProcess postprocess.Chain
+	// HTTPClient sends the requests of post-processors, http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// EventType tells what happened to a video.
+type EventType string
+
+const (
+	EventQueued   EventType = "queued"
+	EventMetadata EventType = "metadata"
+	EventProgress EventType = "progress"
+	EventDone     EventType = "done"
+	EventSkipped  EventType = "skipped"
+	EventError    EventType = "error"
+)
+
+// Event describes the state of a single video download.
+type Event struct {
+	Type    EventType `json:"type"`
+	Time    time.Time `json:"time"`
+	Link    string    `json:"link"`
+	VideoID string    `json:"videoId,omitempty"`
+	Title   string    `json:"title,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Bytes   int64     `json:"bytes"`
+	Total   int64     `json:"total"`
+	// Duration is the length of the video in seconds.
+	Duration float64 `json:"duration,omitempty"`
+	// Elapsed is the time the download took in seconds, set on done events.
+	Elapsed float64 `json:"elapsed,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+func (opts *Options) emit(event Event) {
+	opts.report(event)
+	if opts.OnEvent != nil {
+		event.Time = time.Now()
+		opts.OnEvent(event)
+	}
+}
+
+// report passes event on to opts.Progress, tasks are identified by their link.
+func (opts *Options) report(event Event) {
+	reporter := opts.progress()
+	switch event.Type {
+	case EventQueued:
+		reporter.Stage(event.Link, "queued")
+	case EventMetadata:
+		reporter.Start(event.Link, filepath.Base(event.Path), event.Total)
+	case EventProgress:
+		reporter.Bytes(event.Link, event.Bytes)
+	case EventDone:
+		reporter.Done(event.Link)
+	case EventSkipped:
+		reporter.Stage(event.Link, "skipped")
+		reporter.Done(event.Link)
+	case EventError:
+		reporter.Error(event.Link, errors.New(event.Error))
+	}
+}
+
+func (opts *Options) progress() progress.Reporter {
+	if opts.Progress == nil {
+		return progress.Nop{}
+	}
+	return opts.Progress
+}
+
+func (opts *Options) registry() *extractor.Registry {
+	if opts.Registry == nil {
+		return extractor.Default
+	}
+	return opts.Registry
+}
+
+func (opts *Options) log() io.Writer {
+	if opts.Log == nil {
+		return os.Stdout
+	}
+	return opts.Log
+}
+
+// contextReader stops with ctx's error as soon as ctx is canceled.
+type contextReader struct {
+	io.Reader
+	ctx context.Context
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.Reader.Read(p)
+}
+
+// eventReporter emits the bytes counted by a progress.Reader as progress events of a download.
+type eventReporter struct {
+	progress.Nop
+	event *Event
+	opts  *Options
+}
+
+func (r eventReporter) Bytes(id string, n int64) {
+	r.event.Bytes = n
+	r.opts.emit(*r.event)
+}
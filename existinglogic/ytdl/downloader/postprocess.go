@@ -0,0 +1,41 @@
+package downloader
+
+import (
+	"context"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/postprocess"
+)
+
+// postProcess runs opts.PostProcess on a saved file and returns where the file ended up.
+func postProcess(ctx context.Context,
+	link string,
+	media *extractor.Media,
+	format *models.FormatInfo,
+	index int,
+	path string, opts *Options) (string, error) {
+
+	if len(opts.PostProcess) == 0 {
+		return path, nil
+	}
+
+	opts.progress().Stage(link, "post-processing")
+	item := &postprocess.Item{
+		Path:   path,
+		Video:  media.VideoInfo,
+		Format: *format,
+		Index:  index,
+		Client: opts.HTTPClient,
+		Log:    opts.log(),
+		Stage: func(stage string) {
+			opts.progress().Stage(link, stage)
+		},
+		// converted files of a previous run are handled like downloads
+		Output: opts.OnConflict.Output,
+	}
+	if err := opts.PostProcess.Run(ctx, item); err != nil {
+		return "", err
+	}
+	return item.Path, nil
+}
@@ -0,0 +1,99 @@
+package downloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+// PlaylistInfoFileName is the playlist level sidecar written inside the playlist folder.
+const PlaylistInfoFileName = "playlist.info.json"
+
+// InfoFilePath returns where the .info.json sidecar of a downloaded file lives.
+func InfoFilePath(mediaPath string) string {
+	return strings.TrimSuffix(mediaPath, filepath.Ext(mediaPath)) + ".info.json"
+}
+
+// DescriptionFilePath returns where the .description sidecar of a downloaded file lives.
+func DescriptionFilePath(mediaPath string) string {
+	return strings.TrimSuffix(mediaPath, filepath.Ext(mediaPath)) + ".description"
+}
+
+// ReadInfoFile loads a .info.json sidecar written by a previous run.
+func ReadInfoFile(path string) (*models.InfoFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var info models.InfoFile
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ReadPlaylistInfoFile loads the playlist.info.json sidecar of a playlist folder.
+func ReadPlaylistInfoFile(playlistPath string) (*models.PlaylistInfoFile, error) {
+	data, err := os.ReadFile(filepath.Join(playlistPath, PlaylistInfoFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var info models.PlaylistInfoFile
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// writeSidecars writes the sidecars requested in opts next to mediaPath.
+func writeSidecars(media *extractor.Media, format *models.FormatInfo, index int, mediaPath string, opts *Options) error {
+	if opts.WriteInfoJSON {
+		info := models.InfoFile{
+			VideoInfo:    media.VideoInfo,
+			Format:       *format,
+			Filename:     filepath.Base(mediaPath),
+			DownloadedAt: time.Now(),
+		}
+		info.PlaylistIndex = index
+		if len(opts.PostProcess) > 0 {
+			info.PostProcessed = opts.PostProcess.Names()
+		}
+		if err := writeJSONFile(InfoFilePath(mediaPath), info); err != nil {
+			return err
+		}
+	}
+
+	if opts.WriteDescription {
+		err := os.WriteFile(DescriptionFilePath(mediaPath), []byte(media.Description), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePlaylistSidecar writes playlist.info.json from the playlist listing,
+// entries are not fetched one by one so it stays cheap.
+func writePlaylistSidecar(playlist *extractor.Playlist, playlistPath string) error {
+	info := models.PlaylistInfoFile{
+		PlaylistInfo: playlist.PlaylistInfo,
+		DownloadedAt: time.Now(),
+	}
+	return writeJSONFile(filepath.Join(playlistPath, PlaylistInfoFileName), info)
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
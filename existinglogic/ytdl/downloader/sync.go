@@ -0,0 +1,287 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/rootpath"
+)
+
+// SyncManifestFileName is kept in every synced playlist folder.
+const SyncManifestFileName = ".ytdl-sync.json"
+
+// RemovedFolderName receives the files of entries removed from a synced playlist.
+const RemovedFolderName = ".removed"
+
+// renamingSuffix marks files waiting for their new name while a sync renumbers files.
+const renamingSuffix = ".ytdl-renaming"
+
+// syncTemplate numbers files by playlist position when no template is given,
+// so a changed order shows in the folder.
+const syncTemplate = "{index} - {title}"
+
+// SyncOptions controls what SyncPlaylist does besides downloading new entries.
+type SyncOptions struct {
+	// MoveRemoved moves the files of entries no longer in the playlist to .removed/,
+	// they are left in place otherwise.
+	MoveRemoved bool
+}
+
+// SyncPlaylist makes the playlist folder under opts.OutputDir match the playlist:
+// new entries are downloaded, moved entries are renumbered and removed entries
+// are moved away when syncOpts.MoveRemoved is set.
+// What was saved where is kept in .ytdl-sync.json inside the playlist folder.
+func SyncPlaylist(ctx context.Context, link string, opts Options, syncOpts SyncOptions) []error {
+	ex, err := opts.registry().Find(link)
+	if err != nil {
+		return []error{err}
+	}
+	if !ex.IsPlaylist(link) {
+		return []error{fmt.Errorf("%q is not a playlist link", link)}
+	}
+
+	playlist, err := ex.ExtractPlaylist(ctx, link)
+	if err != nil {
+		return []error{err}
+	}
+
+	playlistFolderName, _ := createPlaylistName(playlist, &opts)
+	playlistPath := filepath.Join(opts.OutputDir, playlistFolderName)
+	if err := rootpath.CreateDirectoryIfNotExists(playlistPath); err != nil {
+		return []error{err}
+	}
+	if opts.Template == "" {
+		opts.Template = syncTemplate
+	}
+	if opts.WritePlaylistMetafiles {
+		if err := writePlaylistSidecar(playlist, playlistPath); err != nil {
+			return []error{err}
+		}
+	}
+
+	manifest, err := ReadSyncManifest(playlistPath)
+	if err != nil {
+		return []error{err}
+	}
+	manifest.PlaylistID = playlist.ID
+	manifest.Url = playlist.Url
+	manifest.Title = playlist.Title
+
+	fmt.Fprintf(opts.log(), "Syncing Playlist %s to: %s...\n\n", playlistFolderName, playlistPath)
+
+	// files deleted by hand are downloaded again
+	for id, entry := range manifest.Entries {
+		if _, err := os.Stat(filepath.Join(playlistPath, entry.Path)); errors.Is(err, fs.ErrNotExist) {
+			delete(manifest.Entries, id)
+		}
+	}
+
+	var errs []error
+	removed := removeSyncEntries(playlistPath, manifest, playlist.Entries, syncOpts, &opts, &errs)
+	renamed := renumberSyncEntries(playlistPath, manifest, playlist.Entries, &opts, &errs)
+	if err := writeSyncManifest(playlistPath, manifest); err != nil {
+		return append(errs, err)
+	}
+
+	downloaded := 0
+	for _, entry := range playlist.Entries {
+		if _, ok := manifest.Entries[entry.ID]; ok {
+			continue
+		}
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+
+		path, video, err := downloadVideoForPlaylist(ctx, ex, entry, playlistPath, &opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("(%d) %s: %w", entry.PlaylistIndex, entry.Title, err))
+			continue
+		}
+		downloaded++
+
+		rel, _ := filepath.Rel(playlistPath, path)
+		manifest.Entries[entry.ID] = models.SyncEntry{Path: rel, Index: entry.PlaylistIndex, Video: video}
+		// saved after every download so an interrupted sync doesn't fetch it again
+		if err := writeSyncManifest(playlistPath, manifest); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	fmt.Fprintf(opts.log(), "\nSynced Playlist to %s: %d downloaded, %d renumbered, %d removed\n",
+		playlistPath, downloaded, renamed, removed)
+	for _, err := range errs {
+		fmt.Fprintf(opts.log(), "\t%v\n", err)
+	}
+	fmt.Fprintln(opts.log())
+
+	return errs
+}
+
+// removeSyncEntries moves away the files of entries missing from the playlist
+// and returns how many were moved.
+func removeSyncEntries(playlistPath string, manifest *models.SyncManifest, entries []models.VideoInfo, syncOpts SyncOptions, opts *Options, errs *[]error) int {
+	if !syncOpts.MoveRemoved {
+		return 0
+	}
+
+	current := make(map[string]bool)
+	for _, entry := range entries {
+		current[entry.ID] = true
+	}
+
+	removed := 0
+	for _, id := range sortedSyncIDs(manifest) {
+		if current[id] {
+			continue
+		}
+		entry := manifest.Entries[id]
+		from := filepath.Join(playlistPath, entry.Path)
+		to := filepath.Join(playlistPath, RemovedFolderName, entry.Path)
+		if err := rootpath.CreateDirectoryIfNotExists(filepath.Dir(to)); err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+		if err := moveMedia(from, to); err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+
+		fmt.Fprintf(opts.log(), "\tRemoved '%s', moved to %s/\n", entry.Path, RemovedFolderName)
+		delete(manifest.Entries, id)
+		removed++
+	}
+	return removed
+}
+
+// renumberSyncEntries renames the files whose playlist position changed
+// and returns how many were renamed.
+func renumberSyncEntries(playlistPath string, manifest *models.SyncManifest, entries []models.VideoInfo, opts *Options, errs *[]error) int {
+	type rename struct {
+		id       string
+		from, to string
+		// tmp is where the file waits while the others are renamed
+		tmp   string
+		index int
+	}
+
+	var renames []rename
+	for _, entry := range entries {
+		synced, ok := manifest.Entries[entry.ID]
+		// a file left under its temporary name by a failed sync is renamed whatever its number
+		waiting := strings.HasSuffix(synced.Path, renamingSuffix)
+		if !ok || synced.Index == entry.PlaylistIndex && !waiting {
+			continue
+		}
+		// only the number changes, the rest of the name comes from the metadata the file was saved with
+		ext := filepath.Ext(strings.TrimSuffix(synced.Path, renamingSuffix))
+		name := videoFileName(playlistPath, &synced.Video, ext, entry.PlaylistIndex, opts)
+		tmp := strings.TrimSuffix(synced.Path, renamingSuffix) + renamingSuffix
+		renames = append(renames, rename{entry.ID, synced.Path, name, tmp, entry.PlaylistIndex})
+	}
+
+	// entries swapping places would overwrite each other, go through temporary names first
+	var staged []rename
+	for _, r := range renames {
+		if r.from == r.tmp {
+			staged = append(staged, r)
+			continue
+		}
+		if err := moveMedia(filepath.Join(playlistPath, r.from), filepath.Join(playlistPath, r.tmp)); err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+		staged = append(staged, r)
+	}
+
+	renamed := 0
+	for _, r := range staged {
+		synced := manifest.Entries[r.id]
+		err := moveMedia(filepath.Join(playlistPath, r.tmp), filepath.Join(playlistPath, r.to))
+		if err == nil {
+			fmt.Fprintf(opts.log(), "\tRenumbered '%s' to (%d)\n", r.to, r.index)
+			synced.Path, synced.Index = r.to, r.index
+			manifest.Entries[r.id] = synced
+			renamed++
+			continue
+		}
+
+		// the file keeps its old name and number, the next sync tries again.
+		// It stays under its temporary name when another file took the old one.
+		*errs = append(*errs, err)
+		if _, err := os.Stat(filepath.Join(playlistPath, r.from)); err == nil {
+			synced.Path = r.tmp
+		} else if err := moveMedia(filepath.Join(playlistPath, r.tmp), filepath.Join(playlistPath, r.from)); err != nil {
+			*errs = append(*errs, err)
+			synced.Path = r.tmp
+		}
+		manifest.Entries[r.id] = synced
+	}
+	return renamed
+}
+
+// moveMedia moves a downloaded file together with its sidecars.
+func moveMedia(from string, to string) error {
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	for _, sidecar := range []func(string) string{InfoFilePath, DescriptionFilePath} {
+		if _, err := os.Stat(sidecar(from)); err == nil {
+			if err := os.Rename(sidecar(from), sidecar(to)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sortedSyncIDs(manifest *models.SyncManifest) []string {
+	ids := make([]string, 0, len(manifest.Entries))
+	for id := range manifest.Entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ReadSyncManifest loads the .ytdl-sync.json of a playlist folder,
+// an empty manifest is returned when the folder was never synced.
+func ReadSyncManifest(playlistPath string) (*models.SyncManifest, error) {
+	manifest := &models.SyncManifest{Entries: map[string]models.SyncEntry{}}
+
+	data, err := os.ReadFile(filepath.Join(playlistPath, SyncManifestFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", SyncManifestFileName, err)
+	}
+	if manifest.Entries == nil {
+		manifest.Entries = map[string]models.SyncEntry{}
+	}
+	return manifest, nil
+}
+
+// writeSyncManifest replaces the manifest only once the new one is fully written.
+func writeSyncManifest(playlistPath string, manifest *models.SyncManifest) error {
+	manifest.SyncedAt = time.Now()
+
+	path := filepath.Join(playlistPath, SyncManifestFileName)
+	if err := writeJSONFile(path+".tmp", manifest); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,143 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/testserver"
+)
+
+// syncFiles lists the files of a synced playlist folder, relative to it, without the manifest.
+func syncFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == SyncManifestFileName {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestSyncPlaylist(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	for _, video := range []testserver.Video{
+		{ID: "aaaaaaaaaaa", Title: "Alpha"},
+		{ID: "bbbbbbbbbbb", Title: "Bravo"},
+		{ID: "ccccccccccc", Title: "Charlie"},
+		{ID: "ddddddddddd", Title: "Delta"},
+	} {
+		srv.AddVideo(video)
+	}
+	srv.AddPlaylist(testserver.Playlist{ID: "PLtest0123456789", Title: "Mix", VideoIDs: []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"}})
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "Mix")
+	opts := Options{OutputDir: root, Log: io.Discard, Registry: extractor.NewDefaultRegistry(srv.Client())}
+	sync := func() []error {
+		return SyncPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PLtest0123456789", opts, SyncOptions{MoveRemoved: true})
+	}
+	check := func(want ...string) {
+		t.Helper()
+		if files := syncFiles(t, dir); strings.Join(files, "|") != strings.Join(want, "|") {
+			t.Errorf("files = %q, want %q", files, want)
+		}
+	}
+
+	if errs := sync(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	check("1 - Alpha.mp4", "2 - Bravo.mp4", "3 - Charlie.mp4")
+
+	// Bravo is removed, Charlie moves up, Alpha moves down, Delta is new.
+	// Alpha was renamed since it was downloaded, its file keeps the name it got.
+	srv.AddVideo(testserver.Video{ID: "aaaaaaaaaaa", Title: "Alpha (remastered)"})
+	srv.AddPlaylist(testserver.Playlist{ID: "PLtest0123456789", Title: "Mix", VideoIDs: []string{"ccccccccccc", "aaaaaaaaaaa", "ddddddddddd"}})
+	if errs := sync(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	check(".removed/2 - Bravo.mp4", "1 - Charlie.mp4", "2 - Alpha.mp4", "3 - Delta.mp4")
+
+	manifest, err := ReadSyncManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Entries) != 3 || manifest.Entries["aaaaaaaaaaa"].Path != "2 - Alpha.mp4" || manifest.Entries["aaaaaaaaaaa"].Index != 2 ||
+		manifest.Entries["ccccccccccc"].Index != 1 || manifest.Entries["ddddddddddd"].Path != "3 - Delta.mp4" {
+		t.Errorf("manifest entries = %+v", manifest.Entries)
+	}
+
+	// nothing changed, nothing happens
+	if errs := sync(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	check(".removed/2 - Bravo.mp4", "1 - Charlie.mp4", "2 - Alpha.mp4", "3 - Delta.mp4")
+}
+
+func TestSyncPlaylistRenameFailure(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.AddVideo(testserver.Video{ID: "aaaaaaaaaaa", Title: "Alpha"})
+	srv.AddVideo(testserver.Video{ID: "bbbbbbbbbbb", Title: "Bravo"})
+	srv.AddPlaylist(testserver.Playlist{ID: "PLtest0123456789", Title: "Mix", VideoIDs: []string{"aaaaaaaaaaa", "bbbbbbbbbbb"}})
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "Mix")
+	opts := Options{OutputDir: root, Log: io.Discard, Registry: extractor.NewDefaultRegistry(srv.Client())}
+	sync := func() []error {
+		return SyncPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PLtest0123456789", opts, SyncOptions{})
+	}
+	if errs := sync(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	// a folder is in the way of Bravo's new name
+	blocker := filepath.Join(dir, "1 - Bravo.mp4")
+	if err := os.MkdirAll(filepath.Join(blocker, "keep"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddPlaylist(testserver.Playlist{ID: "PLtest0123456789", Title: "Mix", VideoIDs: []string{"bbbbbbbbbbb", "aaaaaaaaaaa"}})
+	if errs := sync(); len(errs) != 1 {
+		t.Fatalf("errors = %v, want the failed rename", errs)
+	}
+
+	// Bravo is back under its old name, the manifest still knows it
+	if _, err := os.Stat(filepath.Join(dir, "2 - Bravo.mp4")); err != nil {
+		t.Errorf("Bravo didn't get its name back: %v", err)
+	}
+	manifest, err := ReadSyncManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bravo := manifest.Entries["bbbbbbbbbbb"]; bravo.Path != "2 - Bravo.mp4" || bravo.Index != 2 {
+		t.Errorf("Bravo in the manifest = %+v, want its old name and number", bravo)
+	}
+	if alpha := manifest.Entries["aaaaaaaaaaa"]; alpha.Path != "2 - Alpha.mp4" || alpha.Index != 2 {
+		t.Errorf("Alpha in the manifest = %+v", alpha)
+	}
+
+	// once the folder is gone the next sync renumbers Bravo
+	if err := os.RemoveAll(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if errs := sync(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if files := syncFiles(t, dir); strings.Join(files, "|") != "1 - Bravo.mp4|2 - Alpha.mp4" {
+		t.Errorf("files = %q", files)
+	}
+}
@@ -0,0 +1,28 @@
+package downloader
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+// renderTemplate fills a file name template, the supported fields are:
+//   - {title}  video title
+//   - {author} video author
+//   - {id}     video id
+//   - {index}  position in the playlist (empty for single videos)
+func renderTemplate(template string, vid *models.VideoInfo, index int) string {
+	indexStr := ""
+	if index > 0 {
+		indexStr = strconv.Itoa(index)
+	}
+
+	replacer := strings.NewReplacer(
+		"{title}", vid.Title,
+		"{author}", vid.Author,
+		"{id}", vid.ID,
+		"{index}", indexStr,
+	)
+	return replacer.Replace(template)
+}
@@ -0,0 +1,92 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/rootpath"
+)
+
+// durationTolerance is how many seconds shorter than its metadata a file may be
+// before it's reported as truncated, stream and video durations differ slightly.
+const durationTolerance = 2.0
+
+// mediaExtensions are the files VerifyFile is run on by `ytdl verify`.
+var mediaExtensions = map[string]bool{
+	".mp4": true, ".m4a": true, ".webm": true, ".mkv": true, ".mov": true, ".3gp": true,
+	".flv": true, ".mp3": true, ".aac": true, ".ogg": true, ".opus": true, ".wav": true,
+}
+
+// ErrorCorrupt a downloaded file is incomplete or can't be read.
+type ErrorCorrupt struct {
+	Path   string
+	Reason string
+}
+
+func (e *ErrorCorrupt) Error() string {
+	return fmt.Sprintf("'%s': %s", e.Path, e.Reason)
+}
+
+// IsVerifiable tells whether path is a media file or a leftover partial download.
+func IsVerifiable(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return mediaExtensions[ext] || ext == rootpath.PartSuffix
+}
+
+// VerifyFile checks a downloaded file with ffprobe. When a .info.json sidecar
+// was written next to it, its duration is compared to the metadata too, and so is
+// its size unless the file was post-processed.
+// Leftover .part files are always reported as incomplete.
+func VerifyFile(ctx context.Context, path string) error {
+	if strings.EqualFold(filepath.Ext(path), rootpath.PartSuffix) {
+		return &ErrorCorrupt{path, "incomplete download"}
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if stat.Size() == 0 {
+		return &ErrorCorrupt{path, "empty file"}
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil || stderr.Len() > 0 {
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = err.Error()
+		}
+		return &ErrorCorrupt{path, reason}
+	}
+
+	info, err := ReadInfoFile(InfoFilePath(path))
+	if err != nil {
+		// nothing to compare to
+		return nil
+	}
+	if info.Format.ContentLength > 0 && len(info.PostProcessed) == 0 && stat.Size() != info.Format.ContentLength {
+		return &ErrorCorrupt{path, fmt.Sprintf("%d bytes, expected %d", stat.Size(), info.Format.ContentLength)}
+	}
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err == nil && info.Duration > 0 && duration+durationTolerance < info.Duration {
+		return &ErrorCorrupt{path, fmt.Sprintf("truncated, plays %.0fs of %.0fs", duration, info.Duration)}
+	}
+	return nil
+}
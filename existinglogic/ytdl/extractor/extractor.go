@@ -0,0 +1,94 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// Media is a single downloadable item found by an extractor.
+type Media struct {
+	models.VideoInfo
+	// Source is the extractor's own representation of the media (*youtube.Video for YouTube),
+	// handed back to it by Open.
+	Source any `json:"-"`
+}
+
+// Playlist lists the entries found behind a playlist url.
+// Entries only carry what the listing provides, Extract(entry.Url) fetches the rest.
+type Playlist struct {
+	models.PlaylistInfo
+	Source any `json:"-"`
+}
+
+// Extractor turns urls of one site into downloadable media.
+type Extractor interface {
+	// Name identifies the extractor in messages.
+	Name() string
+	// Match reports whether url is handled by this extractor.
+	Match(url string) bool
+	// IsPlaylist reports whether url points to a playlist rather than a single media.
+	IsPlaylist(url string) bool
+	// Extract fetches the metadata and formats of a single media.
+	Extract(ctx context.Context, url string) (*Media, error)
+	// ExtractPlaylist fetches the metadata and the entries of a playlist.
+	ExtractPlaylist(ctx context.Context, url string) (*Playlist, error)
+	// Open starts streaming format of media, the returned size is -1 when unknown.
+	Open(ctx context.Context, media *Media, format *models.FormatInfo) (io.ReadCloser, int64, error)
+}
+
+// ErrorNoExtractor no registered extractor matches the url.
+type ErrorNoExtractor struct {
+	url string
+}
+
+func (e *ErrorNoExtractor) Error() string {
+	return fmt.Sprintf("no extractor supports the link %q", e.url)
+}
+
+// Registry holds extractors in the order they are tried.
+type Registry struct {
+	mu         sync.RWMutex
+	extractors []Extractor
+}
+
+// NewRegistry creates a registry trying extractors in the given order.
+func NewRegistry(extractors ...Extractor) *Registry {
+	return &Registry{extractors: extractors}
+}
+
+// Register adds an extractor, it's tried after the already registered ones.
+func (r *Registry) Register(extractor Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extractors = append(r.extractors, extractor)
+}
+
+// Find returns the first extractor matching url.
+func (r *Registry) Find(url string) (Extractor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, extractor := range r.extractors {
+		if extractor.Match(url) {
+			return extractor, nil
+		}
+	}
+	return nil, &ErrorNoExtractor{url}
+}
+
+// NewDefaultRegistry creates the registry of all built-in extractors sharing client,
+// a nil client means http.DefaultClient.
+// Site specific extractors go first, the generic one matches any http(s) link.
+func NewDefaultRegistry(client *http.Client) *Registry {
+	return NewRegistry(NewYouTube(&youtube.Client{HTTPClient: client}), NewGeneric(client))
+}
+
+// Default is used by the downloader unless another registry is given.
+var Default = NewDefaultRegistry(nil)
@@ -0,0 +1,149 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/testserver"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// named is an extractor matching every link, it only tells which one the registry picked.
+type named struct {
+	Generic
+	name string
+}
+
+func (n *named) Name() string {
+	return n.name
+}
+
+func (n *named) Match(link string) bool {
+	return true
+}
+
+func TestDefaultRegistryOrder(t *testing.T) {
+	registry := NewDefaultRegistry(nil)
+
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "youtube"},
+		{"https://youtu.be/dQw4w9WgXcQ", "youtube"},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ&list=PL0123456789", "youtube"},
+		{"https://www.youtube.com/playlist?list=PL0123456789", "youtube"},
+		{"https://example.com/clip.mp4", "generic"},
+		{"http://example.com/page.html", "generic"},
+		// only the exact YouTube hosts go to the YouTube extractor
+		{"https://notyoutube.com/watch?v=dQw4w9WgXcQ", "generic"},
+	}
+	for _, tt := range tests {
+		extractor, err := registry.Find(tt.link)
+		if err != nil {
+			t.Errorf("Find(%q) failed: %v", tt.link, err)
+			continue
+		}
+		if extractor.Name() != tt.want {
+			t.Errorf("Find(%q) = %s, want %s", tt.link, extractor.Name(), tt.want)
+		}
+	}
+
+	for _, link := range []string{"ftp://example.com/clip.mp4", "not a link", "/clip.mp4", ""} {
+		var noExtractor *ErrorNoExtractor
+		if _, err := registry.Find(link); !errors.As(err, &noExtractor) {
+			t.Errorf("Find(%q) error = %v, want ErrorNoExtractor", link, err)
+		}
+	}
+}
+
+func TestRegistryTriesExtractorsInOrder(t *testing.T) {
+	first, second := &named{name: "first"}, &named{name: "second"}
+
+	registry := NewRegistry(first)
+	registry.Register(second)
+	if extractor, _ := registry.Find("https://example.com"); extractor != first {
+		t.Errorf("Find picked %s, want the extractor registered first", extractor.Name())
+	}
+
+	// registered after the generic extractor, which matches every http link, it's never used
+	registry = NewDefaultRegistry(nil)
+	registry.Register(first)
+	if extractor, _ := registry.Find("https://example.com"); extractor.Name() != "generic" {
+		t.Errorf("Find picked %s, want generic", extractor.Name())
+	}
+}
+
+func TestMatchAndIsPlaylist(t *testing.T) {
+	youtube, generic := NewYouTube(nil), NewGeneric(nil)
+
+	tests := []struct {
+		link            string
+		youtube         bool
+		youtubePlaylist bool
+		generic         bool
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", true, false, true},
+		{"https://youtube.com/watch?v=dQw4w9WgXcQ", true, false, true},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ", true, false, true},
+		{"https://WWW.YOUTUBE.COM/watch?v=dQw4w9WgXcQ", true, false, true},
+		{"https://youtu.be/dQw4w9WgXcQ", true, false, true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PL0123456789&index=3", true, true, true},
+		{"https://www.youtube.com/playlist?list=PL0123456789", true, true, true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=", true, false, true},
+		{"https://vimeo.com/123456", false, false, true},
+		{"www.youtube.com/watch?v=dQw4w9WgXcQ", false, false, false},
+		{"", false, false, false},
+	}
+	for _, tt := range tests {
+		if got := youtube.Match(tt.link); got != tt.youtube {
+			t.Errorf("youtube.Match(%q) = %v, want %v", tt.link, got, tt.youtube)
+		}
+		if got := youtube.IsPlaylist(tt.link); got != tt.youtubePlaylist {
+			t.Errorf("youtube.IsPlaylist(%q) = %v, want %v", tt.link, got, tt.youtubePlaylist)
+		}
+		if got := generic.Match(tt.link); got != tt.generic {
+			t.Errorf("generic.Match(%q) = %v, want %v", tt.link, got, tt.generic)
+		}
+		if generic.IsPlaylist(tt.link) {
+			t.Errorf("generic.IsPlaylist(%q) = true, the generic extractor has no playlists", tt.link)
+		}
+	}
+}
+
+func TestExtractPlaylist(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.PageSize = 2
+	ids := []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"}
+	for _, id := range ids {
+		srv.AddVideo(testserver.Video{ID: id, Title: "Video " + id, Author: "Ann", Duration: 90})
+	}
+	yt := NewYouTube(&youtube.Client{HTTPClient: srv.Client()})
+
+	// kkdai/youtube drops texts of 2 characters or less, they come from the playlist page
+	for _, p := range []testserver.Playlist{
+		{ID: "PLtest0123456789", Title: "Long enough title", Author: "Some Channel", VideoIDs: ids},
+		{ID: "PLtest0123456789", Title: "PL", Author: "AB", VideoIDs: ids},
+	} {
+		srv.AddPlaylist(p)
+		playlist, err := yt.ExtractPlaylist(context.Background(), "https://www.youtube.com/watch?v=bbbbbbbbbbb&list=PLtest0123456789")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if playlist.ID != p.ID || playlist.Title != p.Title || playlist.Author != p.Author ||
+			playlist.Url != "https://www.youtube.com/playlist?list=PLtest0123456789" {
+			t.Errorf("playlist = %+v, want %q by %q", playlist.PlaylistInfo, p.Title, p.Author)
+		}
+		if len(playlist.Entries) != len(ids) {
+			t.Fatalf("found %d entries, want %d", len(playlist.Entries), len(ids))
+		}
+		for i, entry := range playlist.Entries {
+			if entry.ID != ids[i] || entry.Title != "Video "+ids[i] || entry.PlaylistIndex != i+1 || entry.Duration != 90 {
+				t.Errorf("entry %d = %+v", i, entry)
+			}
+		}
+	}
+}
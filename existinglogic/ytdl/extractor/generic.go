@@ -0,0 +1,254 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+// mediaExtensions are the file extensions recognized as media when the server doesn't say.
+var mediaExtensions = map[string]bool{
+	".mp4": true, ".m4v": true, ".mov": true, ".mkv": true, ".webm": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true,
+	".flac": true, ".wav": true,
+}
+
+// Generic is the fallback extractor for any http(s) link:
+//   - links to media files (https://host/path/file.mp4) are downloaded as they are
+//   - html pages are scanned for <video>/<audio>/<source> tags and og:video/og:audio metadata
+type Generic struct {
+	HTTPClient *http.Client
+}
+
+// NewGeneric creates the generic extractor, a nil client means http.DefaultClient.
+func NewGeneric(client *http.Client) *Generic {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Generic{HTTPClient: client}
+}
+
+func (g *Generic) Name() string {
+	return "generic"
+}
+
+func (g *Generic) Match(link string) bool {
+	parsed, err := url.ParseRequestURI(link)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func (g *Generic) IsPlaylist(link string) bool {
+	return false
+}
+
+// Extract asks the server about the link with a HEAD request,
+// media is used as it is, anything else is fetched and scanned as html.
+func (g *Generic) Extract(ctx context.Context, link string) (*Media, error) {
+	parsed, err := url.ParseRequestURI(link)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	// some servers refuse HEAD, the file extension has to do in that case
+	headOK := resp.StatusCode == http.StatusOK
+	if !headOK && resp.StatusCode != http.StatusMethodNotAllowed {
+		return nil, fmt.Errorf("%q returned http status %q", link, resp.Status)
+	}
+
+	fileName := path.Base(parsed.Path)
+	ext := strings.ToLower(path.Ext(fileName))
+	mimeType := ""
+	if headOK {
+		mimeType = resp.Header.Get("Content-Type")
+	}
+
+	if isMediaType(mimeType) || (!isHTMLType(mimeType) && mediaExtensions[ext]) {
+		if mimeType == "" || !isMediaType(mimeType) {
+			mimeType = mime.TypeByExtension(ext)
+		}
+		var size int64
+		if headOK && resp.ContentLength > 0 {
+			size = resp.ContentLength
+		}
+
+		title := titleFromPath(parsed)
+		return &Media{
+			VideoInfo: models.VideoInfo{
+				ID:    title,
+				Url:   link,
+				Title: title,
+				Formats: []models.FormatInfo{{
+					Itag:          1,
+					MimeType:      mimeType,
+					ContentLength: size,
+					Url:           link,
+					Extension:     extensionFor(link, mimeType),
+				}},
+			},
+		}, nil
+	}
+
+	return g.extractPage(ctx, parsed)
+}
+
+// extractPage downloads the html page and collects every media source it references.
+func (g *Generic) extractPage(ctx context.Context, page *url.URL) (*Media, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, page.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%q returned http status %q", page, resp.Status)
+	}
+
+	// pages larger than this are unlikely to be worth scanning
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	found := scanHTML(string(body))
+	if len(found.sources) == 0 {
+		return nil, fmt.Errorf("no video or audio found on the page %q", page)
+	}
+
+	title := found.title
+	if title == "" {
+		title = titleFromPath(page)
+	}
+
+	media := &Media{
+		VideoInfo: models.VideoInfo{
+			ID:          titleFromPath(page),
+			Url:         page.String(),
+			Title:       title,
+			Description: found.description,
+		},
+	}
+	if found.thumbnail != "" {
+		if thumbnail, err := page.Parse(found.thumbnail); err == nil {
+			media.Thumbnails = []models.Thumbnail{{Url: thumbnail.String()}}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, source := range found.sources {
+		sourceURL, err := page.Parse(source.src)
+		if err != nil || seen[sourceURL.String()] {
+			continue
+		}
+		seen[sourceURL.String()] = true
+
+		mimeType := source.mimeType
+		if mimeType == "" {
+			mimeType = mime.TypeByExtension(strings.ToLower(path.Ext(sourceURL.Path)))
+		}
+		media.Formats = append(media.Formats, models.FormatInfo{
+			Itag:         len(media.Formats) + 1,
+			MimeType:     mimeType,
+			QualityLabel: source.label,
+			Url:          sourceURL.String(),
+			Extension:    extensionFor(sourceURL.String(), mimeType),
+		})
+	}
+
+	return media, nil
+}
+
+func (g *Generic) ExtractPlaylist(ctx context.Context, link string) (*Playlist, error) {
+	return nil, fmt.Errorf("%q is a single media, not a playlist", link)
+}
+
+func (g *Generic) Open(ctx context.Context, media *Media, format *models.FormatInfo) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, format.Url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("%q returned http status %q", format.Url, resp.Status)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+func isMediaType(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "video/") || strings.HasPrefix(mimeType, "audio/")
+}
+
+func isHTMLType(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "text/html") || strings.HasPrefix(mimeType, "application/xhtml")
+}
+
+// titleFromPath names media after the last path element of its link, or the host.
+func titleFromPath(link *url.URL) string {
+	name := path.Base(link.Path)
+	if name == "/" || name == "." {
+		return link.Hostname()
+	}
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return name
+}
+
+// extensionFor prefers the extension in the link, falling back to the mime type.
+func extensionFor(link string, mimeType string) string {
+	if parsed, err := url.Parse(link); err == nil {
+		if ext := strings.ToLower(path.Ext(parsed.Path)); mediaExtensions[ext] {
+			return ext
+		}
+	}
+	return mimeExtension(mimeType)
+}
+
+// mimeExtensions are the extensions of the media types YouTube serves,
+// the system mime table doesn't know some of them or lists odd ones first.
+var mimeExtensions = map[string]string{
+	"video/mp4":   ".mp4",
+	"audio/mp4":   ".m4a",
+	"video/webm":  ".webm",
+	"audio/webm":  ".webm",
+	"video/3gpp":  ".3gp",
+	"video/x-flv": ".flv",
+	"audio/mpeg":  ".mp3",
+}
+
+// mimeExtension returns the extension for a mime type such as `video/webm; codecs="vp9"`,
+// empty when it's unknown.
+func mimeExtension(mimeType string) string {
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	if ext, ok := mimeExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
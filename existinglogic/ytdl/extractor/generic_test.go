@@ -0,0 +1,195 @@
+package extractor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const videoPage = `<!DOCTYPE html>
+<html><head>
+<title>Fallback &amp; Title</title>
+<meta property="og:title" content="Concert &amp; Encore">
+<meta name="description" content="Recorded live">
+<meta property="og:image" content="/img/cover.jpg">
+<meta property="og:video" content="/media/og.mp4">
+<meta property="og:video:type" content="video/mp4">
+</head><body>
+<video controls poster="poster.jpg">
+  <source src="media/720.webm" type="video/webm" label="720p">
+  <source src='../media/480.mp4' size=480>
+  <source src="/media/og.mp4">
+</video>
+<audio src="https://cdn.example.com/track.mp3" type="audio/mpeg"></audio>
+</body></html>`
+
+// newGenericServer serves a media file at /clip.mp4, the same file without an extension
+// at /download, a page with relative media links at /watch/page and a page without any at /empty.
+// HEAD requests are refused with 405 when headAllowed is false.
+func newGenericServer(t *testing.T, headAllowed bool) *httptest.Server {
+	t.Helper()
+	media := strings.Repeat("media", 1000)
+
+	mux := http.NewServeMux()
+	serveMedia := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Header().Set("Content-Length", strconv.Itoa(len(media)))
+		io.WriteString(w, media)
+	}
+	mux.HandleFunc("/clip.mp4", serveMedia)
+	mux.HandleFunc("/download", serveMedia)
+	mux.HandleFunc("/watch/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, videoPage)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><title>Nothing</title></head><body><img src="a.jpg"></body></html>`)
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && !headAllowed {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGenericDirectMedia(t *testing.T) {
+	srv := newGenericServer(t, true)
+	generic := NewGeneric(srv.Client())
+
+	media, err := generic.Extract(context.Background(), srv.URL+"/clip.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media.Title != "clip" || len(media.Formats) != 1 {
+		t.Fatalf("media = %+v, want a single format titled clip", media.VideoInfo)
+	}
+	format := media.Formats[0]
+	if format.Url != srv.URL+"/clip.mp4" || format.MimeType != "video/mp4" || format.Extension != ".mp4" || format.ContentLength != 5000 {
+		t.Errorf("format = %+v, want the link itself, video/mp4, .mp4 and 5000 bytes", format)
+	}
+
+	// the type sent by the server is enough without an extension
+	media, err = generic.Extract(context.Background(), srv.URL+"/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format := media.Formats[0]; format.Extension != ".mp4" || format.Url != srv.URL+"/download" {
+		t.Errorf("format = %+v, want the link itself saved as .mp4", format)
+	}
+
+	reader, size, err := generic.Open(context.Background(), media, &media.Formats[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	data, _ := io.ReadAll(reader)
+	if size != 5000 || len(data) != 5000 {
+		t.Errorf("Open returned %d bytes announced as %d, want 5000", len(data), size)
+	}
+}
+
+func TestGenericHeadNotAllowed(t *testing.T) {
+	srv := newGenericServer(t, false)
+	generic := NewGeneric(srv.Client())
+
+	// the extension has to do
+	media, err := generic.Extract(context.Background(), srv.URL+"/clip.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := media.Formats[0]
+	if format.Url != srv.URL+"/clip.mp4" || format.Extension != ".mp4" || format.ContentLength != 0 {
+		t.Errorf("format = %+v, want the link itself saved as .mp4 with an unknown size", format)
+	}
+
+	// without an extension the link is fetched as a page
+	media, err = generic.Extract(context.Background(), srv.URL+"/watch/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(media.Formats) != 4 {
+		t.Errorf("found %d formats, want 4", len(media.Formats))
+	}
+}
+
+func TestGenericPage(t *testing.T) {
+	srv := newGenericServer(t, true)
+	generic := NewGeneric(srv.Client())
+
+	media, err := generic.Extract(context.Background(), srv.URL+"/watch/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media.Title != "Concert & Encore" || media.Description != "Recorded live" || media.ID != "page" {
+		t.Errorf("title, description, id = %q, %q, %q", media.Title, media.Description, media.ID)
+	}
+	if len(media.Thumbnails) != 1 || media.Thumbnails[0].Url != srv.URL+"/img/cover.jpg" {
+		t.Errorf("thumbnails = %+v, want %s/img/cover.jpg", media.Thumbnails, srv.URL)
+	}
+
+	// relative links are resolved against the page, duplicates are dropped
+	want := []struct {
+		url, mimeType, label, ext string
+	}{
+		{srv.URL + "/media/og.mp4", "video/mp4", "", ".mp4"},
+		{srv.URL + "/watch/media/720.webm", "video/webm", "720p", ".webm"},
+		// the type of the system mime table, if any
+		{srv.URL + "/media/480.mp4", "", "480", ".mp4"},
+		{"https://cdn.example.com/track.mp3", "audio/mpeg", "", ".mp3"},
+	}
+	if len(media.Formats) != len(want) {
+		t.Fatalf("found %d formats, want %d: %+v", len(media.Formats), len(want), media.Formats)
+	}
+	for i, w := range want {
+		format := media.Formats[i]
+		if format.Itag != i+1 || format.Url != w.url || (w.mimeType != "" && format.MimeType != w.mimeType) ||
+			format.QualityLabel != w.label || format.Extension != w.ext {
+			t.Errorf("format %d = %+v, want %+v", i, format, w)
+		}
+	}
+}
+
+func TestGenericPageWithoutMedia(t *testing.T) {
+	srv := newGenericServer(t, true)
+	generic := NewGeneric(srv.Client())
+
+	_, err := generic.Extract(context.Background(), srv.URL+"/empty")
+	if err == nil || !strings.Contains(err.Error(), "no video or audio found") {
+		t.Errorf("error = %v, want no video or audio found", err)
+	}
+
+	_, err = generic.Extract(context.Background(), srv.URL+"/missing")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("error = %v, want the 404 status", err)
+	}
+}
+
+func TestScanHTML(t *testing.T) {
+	found := scanHTML(`<title> Only &lt;Title&gt; </title>
+		<VIDEO SRC="a.mp4"></VIDEO>
+		<video><source type="audio/ogg"></video>
+		<meta property="og:audio" content="b.ogg"><meta property="og:audio:type" content="audio/ogg">`)
+
+	if found.title != "Only <Title>" {
+		t.Errorf("title = %q, want the unescaped <title>", found.title)
+	}
+	want := []htmlSource{{src: "a.mp4"}, {src: "b.ogg", mimeType: "audio/ogg"}}
+	if len(found.sources) != len(want) {
+		t.Fatalf("sources = %+v, want %+v", found.sources, want)
+	}
+	for i := range want {
+		if found.sources[i] != want[i] {
+			t.Errorf("source %d = %+v, want %+v", i, found.sources[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package extractor
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	tagRegex       = regexp.MustCompile(`(?is)<(video|audio|source|meta)\b([^>]*)>`)
+	titleRegex     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	attributeRegex = regexp.MustCompile(`(?s)([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// htmlSource is a media link found in a page.
+type htmlSource struct {
+	src      string
+	mimeType string
+	label    string
+}
+
+// htmlMedia is everything scanHTML found in a page.
+type htmlMedia struct {
+	title       string
+	description string
+	thumbnail   string
+	sources     []htmlSource
+}
+
+// scanHTML collects media sources from <video>, <audio> and <source> tags
+// and from the og:video/og:audio metadata, in the order they appear.
+// It's a tag scanner rather than a parser, good enough for the markup media players use.
+func scanHTML(body string) htmlMedia {
+	var found htmlMedia
+
+	for _, match := range tagRegex.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(match[1])
+		attrs := parseAttributes(match[2])
+
+		switch tag {
+		case "video", "audio", "source":
+			if attrs["src"] == "" {
+				continue
+			}
+			label := attrs["label"]
+			if label == "" {
+				label = attrs["size"]
+			}
+			found.sources = append(found.sources, htmlSource{
+				src:      attrs["src"],
+				mimeType: attrs["type"],
+				label:    label,
+			})
+		case "meta":
+			property := strings.ToLower(attrs["property"])
+			if property == "" {
+				property = strings.ToLower(attrs["name"])
+			}
+			content := attrs["content"]
+			switch property {
+			case "og:video", "og:video:url", "og:video:secure_url", "og:audio", "og:audio:url", "og:audio:secure_url":
+				if content != "" {
+					found.sources = append(found.sources, htmlSource{src: content})
+				}
+			case "og:video:type", "og:audio:type":
+				// the type belongs to the og source right before it
+				if n := len(found.sources); n > 0 && found.sources[n-1].mimeType == "" {
+					found.sources[n-1].mimeType = content
+				}
+			case "og:title":
+				found.title = content
+			case "og:description", "description":
+				if found.description == "" {
+					found.description = content
+				}
+			case "og:image":
+				found.thumbnail = content
+			}
+		}
+	}
+
+	if found.title == "" {
+		if match := titleRegex.FindStringSubmatch(body); match != nil {
+			found.title = strings.TrimSpace(html.UnescapeString(match[1]))
+		}
+	}
+
+	return found
+}
+
+// parseAttributes returns the lower cased attribute names with their unescaped values.
+func parseAttributes(raw string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range attributeRegex.FindAllStringSubmatch(raw, -1) {
+		value := match[2] + match[3] + match[4]
+		attrs[strings.ToLower(match[1])] = html.UnescapeString(value)
+	}
+	return attrs
+}
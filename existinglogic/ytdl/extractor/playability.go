@@ -0,0 +1,129 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// ErrorPrivate the uploader made the video private.
+type ErrorPrivate struct {
+	VideoID string
+}
+
+func (e *ErrorPrivate) Error() string {
+	return fmt.Sprintf("video %q is private: only the uploader and invited accounts can watch it, pass --cookies of such an account", e.VideoID)
+}
+
+// ErrorAgeRestricted the video can only be watched signed in to an adult account.
+type ErrorAgeRestricted struct {
+	VideoID string
+}
+
+func (e *ErrorAgeRestricted) Error() string {
+	return fmt.Sprintf("video %q is age-restricted: pass --cookies of a signed in account", e.VideoID)
+}
+
+// ErrorMembersOnly the video is reserved to members of the channel.
+type ErrorMembersOnly struct {
+	VideoID string
+}
+
+func (e *ErrorMembersOnly) Error() string {
+	return fmt.Sprintf("video %q is members-only: pass --cookies of an account that joined the channel", e.VideoID)
+}
+
+// ErrorGeoBlocked the video is not available in the country the request comes from.
+type ErrorGeoBlocked struct {
+	VideoID string
+}
+
+func (e *ErrorGeoBlocked) Error() string {
+	return fmt.Sprintf("video %q is not available in your country", e.VideoID)
+}
+
+// ErrorUnplayable any other reason YouTube gives for not playing a video,
+// e.g. removed videos, login checks or upcoming premieres.
+type ErrorUnplayable struct {
+	VideoID string
+	Status  string
+	Reason  string
+}
+
+func (e *ErrorUnplayable) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("video %q can't be played (%s)", e.VideoID, e.Status)
+	}
+	return fmt.Sprintf("video %q can't be played (%s): %s", e.VideoID, e.Status, e.Reason)
+}
+
+// PlayabilityError maps the playabilityStatus of a player response onto the errors above.
+// It returns nil for playable videos.
+func PlayabilityError(videoID string, status models.PlayabilityStatus) error {
+	if status.Status == "" || status.Status == "OK" {
+		return nil
+	}
+
+	renderer := status.ErrorScreen.PlayerErrorMessageRenderer
+	reason := status.Reason
+	if reason == "" {
+		reason = renderer.Reason.String()
+	}
+	// the subreason tells apart geo blocking from other "Video unavailable" cases
+	details := strings.ToLower(reason + " " + renderer.Subreason.String())
+
+	switch {
+	case strings.Contains(details, "private"):
+		return &ErrorPrivate{videoID}
+	case strings.Contains(details, "confirm your age") || strings.Contains(details, "age-restricted"):
+		return &ErrorAgeRestricted{videoID}
+	case strings.Contains(details, "members"):
+		return &ErrorMembersOnly{videoID}
+	case strings.Contains(details, "your country"):
+		return &ErrorGeoBlocked{videoID}
+	}
+
+	if subreason := renderer.Subreason.String(); subreason != "" && subreason != reason {
+		reason = reason + ": " + subreason
+	}
+	return &ErrorUnplayable{VideoID: videoID, Status: status.Status, Reason: reason}
+}
+
+// playabilityError explains why the youtube client refused to play the video with the playabilityStatus
+// of its watch page, the client only keeps part of it. youtubeError maps err when the page can't tell.
+func (yt *YouTube) playabilityError(ctx context.Context, videoID string, err error) error {
+	var status *youtube.ErrPlayabiltyStatus
+	if !errors.Is(err, youtube.ErrVideoPrivate) && !errors.Is(err, youtube.ErrLoginRequired) &&
+		!errors.Is(err, youtube.ErrNotPlayableInEmbed) && !errors.As(err, &status) {
+		return err
+	}
+
+	if response, pageErr := yt.playerResponse(ctx, videoID); pageErr == nil {
+		if playabilityErr := PlayabilityError(videoID, response.PlayabilityStatus); playabilityErr != nil {
+			return playabilityErr
+		}
+	}
+	return youtubeError(videoID, err)
+}
+
+// youtubeError maps the playability errors of the youtube client onto the errors above,
+// anything else is returned unchanged. The client reports every LOGIN_REQUIRED status but private videos
+// as ErrLoginRequired, be it an age check, a members-only video or a bot check, so that one stays unexplained.
+func youtubeError(videoID string, err error) error {
+	var status *youtube.ErrPlayabiltyStatus
+	switch {
+	case errors.Is(err, youtube.ErrVideoPrivate):
+		return &ErrorPrivate{videoID}
+	case errors.Is(err, youtube.ErrLoginRequired):
+		return &ErrorUnplayable{VideoID: videoID, Status: "LOGIN_REQUIRED", Reason: "sign in required, pass --cookies of a signed in account"}
+	case errors.As(err, &status):
+		return PlayabilityError(videoID, models.PlayabilityStatus{Status: status.Status, Reason: status.Reason})
+	default:
+		return err
+	}
+}
@@ -0,0 +1,133 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/testserver"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// errorScreen builds the playabilityStatus of an unplayable video the way the watch page has it.
+func errorScreen(status, reason, subreason string) models.PlayabilityStatus {
+	var s models.PlayabilityStatus
+	s.Status, s.Reason = status, reason
+	s.ErrorScreen.PlayerErrorMessageRenderer.Reason.SimpleText = reason
+	s.ErrorScreen.PlayerErrorMessageRenderer.Subreason.SimpleText = subreason
+	return s
+}
+
+func TestPlayabilityError(t *testing.T) {
+	var (
+		private       *ErrorPrivate
+		ageRestricted *ErrorAgeRestricted
+		membersOnly   *ErrorMembersOnly
+		geoBlocked    *ErrorGeoBlocked
+		unplayable    *ErrorUnplayable
+	)
+	// the reason may only be in the error screen
+	geoScreen := errorScreen("UNPLAYABLE", "", "The uploader has not made this video available in your country")
+	geoScreen.ErrorScreen.PlayerErrorMessageRenderer.Reason.SimpleText = "Video unavailable"
+
+	tests := []struct {
+		name   string
+		status models.PlayabilityStatus
+		target any
+		// message is part of the error
+		message string
+	}{
+		{"playable", models.PlayabilityStatus{Status: "OK"}, nil, ""},
+		{"no status", models.PlayabilityStatus{}, nil, ""},
+		{"private", errorScreen("LOGIN_REQUIRED", "This video is private", "If the owner of this video has granted you access, please sign in."), &private, "pass --cookies"},
+		{"age-restricted", errorScreen("LOGIN_REQUIRED", "Sign in to confirm your age", "This video may be inappropriate for some users."), &ageRestricted, "age-restricted: pass --cookies"},
+		{"age-restricted without a screen", models.PlayabilityStatus{Status: "LOGIN_REQUIRED", Reason: "This video is age-restricted"}, &ageRestricted, "age-restricted"},
+		{"members-only", errorScreen("UNPLAYABLE", "Join this channel to get access to members-only content like this video, and other exclusive perks.", ""), &membersOnly, "members-only"},
+		{"region-blocked", errorScreen("UNPLAYABLE", "Video unavailable", "The uploader has not made this video available in your country"), &geoBlocked, "not available in your country"},
+		{"region-blocked in the error screen", geoScreen, &geoBlocked, "not available in your country"},
+		{"live", models.PlayabilityStatus{Status: "LIVE_STREAM_OFFLINE", Reason: "This live event will begin in 3 hours."}, &unplayable, "(LIVE_STREAM_OFFLINE): This live event will begin in 3 hours."},
+		{"removed", errorScreen("ERROR", "This video has been removed by the uploader", ""), &unplayable, "removed by the uploader"},
+		{"bot check", errorScreen("LOGIN_REQUIRED", "Sign in to confirm you're not a bot", "This helps protect our community."), &unplayable, "not a bot: This helps protect our community."},
+	}
+	for _, tt := range tests {
+		err := PlayabilityError("abcdefghijk", tt.status)
+		if tt.target == nil {
+			if err != nil {
+				t.Errorf("%s: error = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		if !errors.As(err, tt.target) {
+			t.Errorf("%s: error = %T %v, want %T", tt.name, err, err, tt.target)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.message) || !strings.Contains(err.Error(), "abcdefghijk") {
+			t.Errorf("%s: error %q doesn't tell the video id and %q", tt.name, err, tt.message)
+		}
+	}
+}
+
+func TestYoutubeError(t *testing.T) {
+	var (
+		private       *ErrorPrivate
+		ageRestricted *ErrorAgeRestricted
+		geoBlocked    *ErrorGeoBlocked
+		unplayable    *ErrorUnplayable
+	)
+	other := errors.New("connection reset")
+
+	tests := []struct {
+		name   string
+		err    error
+		target any
+	}{
+		{"private", youtube.ErrVideoPrivate, &private},
+		// a login isn't necessarily about the age of the viewer
+		{"login required", fmt.Errorf("can't bypass age restriction: %w", youtube.ErrLoginRequired), &unplayable},
+		{"region-blocked", &youtube.ErrPlayabiltyStatus{Status: "UNPLAYABLE", Reason: "The uploader has not made this video available in your country"}, &geoBlocked},
+		{"age-restricted", &youtube.ErrPlayabiltyStatus{Status: "UNPLAYABLE", Reason: "Sign in to confirm your age"}, &ageRestricted},
+		{"live", &youtube.ErrPlayabiltyStatus{Status: "LIVE_STREAM_OFFLINE", Reason: "This live event will begin in 3 hours."}, &unplayable},
+	}
+	for _, tt := range tests {
+		if err := youtubeError("abcdefghijk", tt.err); !errors.As(err, tt.target) {
+			t.Errorf("%s: error = %T %v, want %T", tt.name, err, err, tt.target)
+		}
+	}
+	if err := youtubeError("abcdefghijk", other); err != other {
+		t.Errorf("error = %v, want other errors unchanged", err)
+	}
+}
+
+// TestExtractUnplayable goes through the youtube client, the reason comes from the watch page.
+func TestExtractUnplayable(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	yt := NewYouTube(&youtube.Client{HTTPClient: srv.Client()})
+
+	var (
+		private       *ErrorPrivate
+		ageRestricted *ErrorAgeRestricted
+		geoBlocked    *ErrorGeoBlocked
+		unplayable    *ErrorUnplayable
+	)
+	tests := []struct {
+		video  testserver.Video
+		target any
+	}{
+		{testserver.Video{ID: "pr1vat3v1d0", Status: "LOGIN_REQUIRED", Reason: "This video is private"}, &private},
+		{testserver.Video{ID: "ag3r3str1ct", Status: "LOGIN_REQUIRED", Reason: "Sign in to confirm your age"}, &ageRestricted},
+		{testserver.Video{ID: "g30bl0ck3d1", Status: "UNPLAYABLE", Reason: "The uploader has not made this video available in your country"}, &geoBlocked},
+		{testserver.Video{ID: "l1v3str3am1", Status: "LIVE_STREAM_OFFLINE", Reason: "This live event will begin in 3 hours."}, &unplayable},
+	}
+	for _, tt := range tests {
+		srv.AddVideo(tt.video)
+		_, err := yt.Extract(context.Background(), "https://www.youtube.com/watch?v="+tt.video.ID)
+		if !errors.As(err, tt.target) {
+			t.Errorf("%s: error = %T %v, want %T", tt.video.ID, err, err, tt.target)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package extractor
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/parser"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// YouTube extracts videos and playlists using github.com/kkdai/youtube.
+type YouTube struct {
+	Client *youtube.Client
+}
+
+// NewYouTube creates the YouTube extractor, a nil client means a default one.
+func NewYouTube(client *youtube.Client) *YouTube {
+	if client == nil {
+		client = &youtube.Client{}
+	}
+	return &YouTube{Client: client}
+}
+
+func (yt *YouTube) Name() string {
+	return "youtube"
+}
+
+func (yt *YouTube) Match(link string) bool {
+	parsed, err := url.ParseRequestURI(link)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(parsed.Hostname()) {
+	case "youtube.com", "www.youtube.com", "m.youtube.com", "music.youtube.com", "youtu.be":
+		return true
+	default:
+		return false
+	}
+}
+
+func (yt *YouTube) IsPlaylist(link string) bool {
+	videoLinkParsed, err := parser.ParseVideoUrl(link)
+	if err != nil {
+		return false
+	}
+	return len(videoLinkParsed.Playlist) > 0
+}
+
+func (yt *YouTube) Extract(ctx context.Context, link string) (*Media, error) {
+	video, err := yt.Client.GetVideoContext(ctx, link)
+	if err != nil {
+		videoID, idErr := youtube.ExtractVideoID(link)
+		if idErr != nil {
+			videoID = link
+		}
+		return nil, yt.playabilityError(ctx, videoID, err)
+	}
+	return &Media{VideoInfo: newVideoInfo(video), Source: video}, nil
+}
+
+func (yt *YouTube) ExtractPlaylist(ctx context.Context, link string) (*Playlist, error) {
+	// links of a video inside a playlist are turned into the playlist link
+	playlistLink, err := parser.ConvertVideoLinkToPlaylistLink(link)
+	if err != nil {
+		return nil, err
+	}
+	playlistLinkParsed, err := parser.ParsePlaylistUrl(playlistLink)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist, err := yt.Client.GetPlaylistContext(ctx, playlistLinkParsed.PlaylistId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Playlist{
+		PlaylistInfo: models.PlaylistInfo{
+			ID:          playlist.ID,
+			Url:         playlistLink,
+			Title:       playlist.Title,
+			Author:      playlist.Author,
+			Description: playlist.Description,
+			Entries:     []models.VideoInfo{},
+		},
+		Source: playlist,
+	}
+	// kkdai/youtube drops texts of 2 characters or less, like a playlist titled "PL",
+	// and only reads the owner where the web client has it
+	if result.Title == "" || result.Author == "" {
+		if data, err := yt.playlistData(ctx, playlistLink); err == nil {
+			result.Title = cmp.Or(result.Title, data.Title())
+			result.Author = cmp.Or(result.Author, data.Author())
+		}
+	}
+	for index, entry := range playlist.Videos {
+		result.Entries = append(result.Entries, models.VideoInfo{
+			ID:            entry.ID,
+			Url:           videoLink(entry.ID),
+			Title:         entry.Title,
+			Author:        entry.Author,
+			Duration:      entry.Duration.Seconds(),
+			PlaylistIndex: index + 1,
+		})
+	}
+
+	return result, nil
+}
+
+func (yt *YouTube) Open(ctx context.Context, media *Media, format *models.FormatInfo) (io.ReadCloser, int64, error) {
+	video, ok := media.Source.(*youtube.Video)
+	if !ok {
+		return nil, 0, fmt.Errorf("media %q was not extracted by the youtube extractor", media.ID)
+	}
+
+	formats := video.Formats.Itag(format.Itag)
+	if len(formats) == 0 {
+		return nil, 0, fmt.Errorf("format %d not found for video %q", format.Itag, media.ID)
+	}
+
+	return yt.Client.GetStreamContext(ctx, video, &formats[0])
+}
+
+// newVideoInfo converts the youtube client metadata into models.VideoInfo.
+func newVideoInfo(video *youtube.Video) models.VideoInfo {
+	info := models.VideoInfo{
+		ID:          video.ID,
+		Url:         videoLink(video.ID),
+		Title:       video.Title,
+		Author:      video.Author,
+		ChannelId:   video.ChannelID,
+		Duration:    video.Duration.Seconds(),
+		Views:       video.Views,
+		Description: video.Description,
+	}
+	if !video.PublishDate.IsZero() {
+		info.PublishDate = video.PublishDate.Format("2006-01-02")
+	}
+
+	for _, thumbnail := range video.Thumbnails {
+		info.Thumbnails = append(info.Thumbnails, models.Thumbnail{
+			Url:    thumbnail.URL,
+			Width:  thumbnail.Width,
+			Height: thumbnail.Height,
+		})
+	}
+
+	for _, format := range video.Formats {
+		info.Formats = append(info.Formats, newFormatInfo(&format))
+	}
+
+	return info
+}
+
+// newFormatInfo converts a single youtube format into models.FormatInfo.
+func newFormatInfo(format *youtube.Format) models.FormatInfo {
+	return models.FormatInfo{
+		Itag:            format.ItagNo,
+		MimeType:        format.MimeType,
+		Quality:         format.Quality,
+		QualityLabel:    format.QualityLabel,
+		Bitrate:         format.Bitrate,
+		AverageBitrate:  format.AverageBitrate,
+		ContentLength:   format.ContentLength,
+		Width:           format.Width,
+		Height:          format.Height,
+		FPS:             format.FPS,
+		AudioQuality:    format.AudioQuality,
+		AudioChannels:   format.AudioChannels,
+		AudioSampleRate: format.AudioSampleRate,
+		Extension:       mimeExtension(format.MimeType),
+	}
+}
+
+// playerResponse fetches the watch page of the video and parses the player response it embeds.
+func (yt *YouTube) playerResponse(ctx context.Context, videoID string) (*models.PlayerResponse, error) {
+	page, err := yt.page(ctx, videoLink(videoID))
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParsePlayerResponse(page)
+}
+
+// playlistData fetches the page of the playlist and parses the initial data it embeds.
+func (yt *YouTube) playlistData(ctx context.Context, link string) (*models.PlaylistData, error) {
+	page, err := yt.page(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParsePlaylistData(page)
+}
+
+// page fetches a YouTube page with the client's HTTP client.
+func (yt *YouTube) page(ctx context.Context, link string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	client := yt.Client.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%q returned http status %q", req.URL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	return string(body), err
+}
+
+func videoLink(id string) string {
+	return "https://www.youtube.com/watch?v=" + id
+}
@@ -1,12 +1,15 @@
-module ytdl
+module github.com/mtdem/ytdownloader/existinglogic/ytdl
 
 go 1.23.2
 
 require (
 	github.com/gosimple/slug v1.14.0
+	github.com/gosimple/unidecode v1.0.1
+	github.com/gosuri/uilive v0.0.4
 	github.com/gosuri/uiprogress v0.0.1
 	github.com/kkdai/youtube/v2 v2.10.1
 	github.com/spf13/cobra v1.8.1
+	golang.org/x/net v0.34.0
 )
 
 require (
@@ -15,11 +18,9 @@ require (
 	github.com/dop251/goja v0.0.0-20240220182346-e401ed450204 // indirect
 	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
 	github.com/google/pprof v0.0.0-20240227163752-401108e1b7e7 // indirect
-	github.com/gosimple/unidecode v1.0.1 // indirect
-	github.com/gosuri/uilive v0.0.4 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.18.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
 )
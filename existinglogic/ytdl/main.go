@@ -1,8 +1,7 @@
 package main
 
-import "ytdl/cmd"
+import "github.com/mtdem/ytdownloader/existinglogic/ytdl/cmd"
 
 func main() {
-	// cmd.Execute()
-	cmd.RunTestDownload()
+	cmd.Execute()
 }
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type VideoLinkParsed struct {
 	Url           string
 	VideoId       string
@@ -12,3 +14,93 @@ type PlaylistLinkParsed struct {
 	Url        string
 	PlaylistId string
 }
+
+// VideoInfo is the metadata of a single video, as printed by `ytdl info`.
+type VideoInfo struct {
+	ID            string       `json:"id"`
+	Url           string       `json:"url"`
+	Title         string       `json:"title"`
+	Author        string       `json:"author"`
+	ChannelId     string       `json:"channelId,omitempty"`
+	Duration      float64      `json:"duration"`
+	Views         int          `json:"views,omitempty"`
+	PublishDate   string       `json:"publishDate,omitempty"`
+	Description   string       `json:"description,omitempty"`
+	PlaylistIndex int          `json:"playlistIndex,omitempty"`
+	Thumbnails    []Thumbnail  `json:"thumbnails,omitempty"`
+	Formats       []FormatInfo `json:"formats,omitempty"`
+	// Error is set on playlist entries whose metadata couldn't be fetched.
+	Error string `json:"error,omitempty"`
+}
+
+type Thumbnail struct {
+	Url    string `json:"url"`
+	Width  uint   `json:"width"`
+	Height uint   `json:"height"`
+}
+
+// FormatInfo describes one stream a video can be downloaded as.
+type FormatInfo struct {
+	Itag            int    `json:"itag"`
+	MimeType        string `json:"mimeType"`
+	Quality         string `json:"quality"`
+	QualityLabel    string `json:"qualityLabel,omitempty"`
+	Bitrate         int    `json:"bitrate"`
+	AverageBitrate  int    `json:"averageBitrate,omitempty"`
+	ContentLength   int64  `json:"contentLength,omitempty"`
+	Width           int    `json:"width,omitempty"`
+	Height          int    `json:"height,omitempty"`
+	FPS             int    `json:"fps,omitempty"`
+	AudioQuality    string `json:"audioQuality,omitempty"`
+	AudioChannels   int    `json:"audioChannels,omitempty"`
+	AudioSampleRate string `json:"audioSampleRate,omitempty"`
+	// Url is set by extractors that download formats straight from a link.
+	Url string `json:"url,omitempty"`
+	// Extension of the saved file, including the dot.
+	Extension string `json:"ext,omitempty"`
+}
+
+// PlaylistInfo is the metadata of a playlist and all of its entries.
+type PlaylistInfo struct {
+	ID          string      `json:"id"`
+	Url         string      `json:"url"`
+	Title       string      `json:"title"`
+	Author      string      `json:"author"`
+	Description string      `json:"description,omitempty"`
+	Entries     []VideoInfo `json:"entries"`
+}
+
+// InfoFile is the content of the <name>.info.json sidecar written next to a download,
+// it holds everything needed to rebuild tags or file names without going online.
+type InfoFile struct {
+	VideoInfo
+	Format       FormatInfo `json:"format"`
+	Filename     string     `json:"filename"`
+	DownloadedAt time.Time  `json:"downloadedAt"`
+	// PostProcessed lists the post-processors run on the file, its size no longer matches Format then.
+	PostProcessed []string `json:"postProcessed,omitempty"`
+}
+
+// PlaylistInfoFile is the content of the playlist.info.json sidecar of a playlist folder.
+type PlaylistInfoFile struct {
+	PlaylistInfo
+	DownloadedAt time.Time `json:"downloadedAt"`
+}
+
+// SyncManifest is the content of the .ytdl-sync.json file `ytdl sync` keeps in a playlist folder.
+type SyncManifest struct {
+	PlaylistID string    `json:"playlistId"`
+	Url        string    `json:"url"`
+	Title      string    `json:"title"`
+	SyncedAt   time.Time `json:"syncedAt"`
+	// Entries maps video ids to the files they were saved to.
+	Entries map[string]SyncEntry `json:"entries"`
+}
+
+type SyncEntry struct {
+	// Path is relative to the playlist folder.
+	Path  string `json:"path"`
+	Index int    `json:"index"`
+	// Video is the metadata the file was named from, renumbering keeps all but the index.
+	Video VideoInfo `json:"video"`
+}
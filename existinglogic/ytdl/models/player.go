@@ -0,0 +1,140 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PlayerResponse is the ytInitialPlayerResponse object embedded in YouTube watch pages.
+// Only the parts ytdl uses are modeled, everything else is ignored when decoding.
+type PlayerResponse struct {
+	PlayabilityStatus PlayabilityStatus `json:"playabilityStatus"`
+	StreamingData     StreamingData     `json:"streamingData"`
+	VideoDetails      VideoDetails      `json:"videoDetails"`
+	Captions          Captions          `json:"captions"`
+	Microformat       Microformat       `json:"microformat"`
+}
+
+// PlayabilityStatus tells whether the video can be played, and why not.
+// Status is "OK" for playable videos, otherwise e.g. "LOGIN_REQUIRED", "UNPLAYABLE" or "ERROR".
+type PlayabilityStatus struct {
+	Status          string      `json:"status"`
+	Reason          string      `json:"reason"`
+	PlayableInEmbed bool        `json:"playableInEmbed"`
+	ErrorScreen     ErrorScreen `json:"errorScreen"`
+}
+
+type ErrorScreen struct {
+	PlayerErrorMessageRenderer struct {
+		Reason    Text `json:"reason"`
+		Subreason Text `json:"subreason"`
+	} `json:"playerErrorMessageRenderer"`
+}
+
+// Text is YouTube's formatted text, either a simpleText or a list of runs.
+type Text struct {
+	SimpleText string `json:"simpleText"`
+	Runs       []struct {
+		Text string `json:"text"`
+	} `json:"runs"`
+}
+
+func (t Text) String() string {
+	if t.SimpleText != "" {
+		return t.SimpleText
+	}
+
+	var text strings.Builder
+	for _, run := range t.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+type StreamingData struct {
+	ExpiresInSeconds string         `json:"expiresInSeconds"`
+	Formats          []PlayerFormat `json:"formats"`
+	AdaptiveFormats  []PlayerFormat `json:"adaptiveFormats"`
+}
+
+// PlayerFormat is a single stream of StreamingData.
+// Url is empty for protected videos, their SignatureCipher has to be deciphered first.
+type PlayerFormat struct {
+	Itag             int    `json:"itag"`
+	Url              string `json:"url"`
+	SignatureCipher  string `json:"signatureCipher"`
+	MimeType         string `json:"mimeType"`
+	Bitrate          int    `json:"bitrate"`
+	AverageBitrate   int    `json:"averageBitrate"`
+	Width            int    `json:"width"`
+	Height           int    `json:"height"`
+	ContentLength    string `json:"contentLength"`
+	Quality          string `json:"quality"`
+	QualityLabel     string `json:"qualityLabel"`
+	FPS              int    `json:"fps"`
+	AudioQuality     string `json:"audioQuality"`
+	AudioSampleRate  string `json:"audioSampleRate"`
+	AudioChannels    int    `json:"audioChannels"`
+	ApproxDurationMs string `json:"approxDurationMs"`
+}
+
+// FormatInfo converts the player format into the format printed by `ytdl info`.
+func (f PlayerFormat) FormatInfo() FormatInfo {
+	contentLength, _ := strconv.ParseInt(f.ContentLength, 10, 64)
+	return FormatInfo{
+		Itag:            f.Itag,
+		MimeType:        f.MimeType,
+		Quality:         f.Quality,
+		QualityLabel:    f.QualityLabel,
+		Bitrate:         f.Bitrate,
+		AverageBitrate:  f.AverageBitrate,
+		ContentLength:   contentLength,
+		Width:           f.Width,
+		Height:          f.Height,
+		FPS:             f.FPS,
+		AudioQuality:    f.AudioQuality,
+		AudioChannels:   f.AudioChannels,
+		AudioSampleRate: f.AudioSampleRate,
+		Url:             f.Url,
+	}
+}
+
+type VideoDetails struct {
+	VideoId          string `json:"videoId"`
+	Title            string `json:"title"`
+	LengthSeconds    string `json:"lengthSeconds"`
+	ChannelId        string `json:"channelId"`
+	ShortDescription string `json:"shortDescription"`
+	Author           string `json:"author"`
+	ViewCount        string `json:"viewCount"`
+	IsPrivate        bool   `json:"isPrivate"`
+	IsLiveContent    bool   `json:"isLiveContent"`
+	Thumbnail        struct {
+		Thumbnails []Thumbnail `json:"thumbnails"`
+	} `json:"thumbnail"`
+}
+
+type Captions struct {
+	PlayerCaptionsTracklistRenderer struct {
+		CaptionTracks []CaptionTrack `json:"captionTracks"`
+	} `json:"playerCaptionsTracklistRenderer"`
+}
+
+// CaptionTrack is a single subtitle track, BaseUrl serves it as timed text XML.
+type CaptionTrack struct {
+	BaseUrl        string `json:"baseUrl"`
+	Name           Text   `json:"name"`
+	LanguageCode   string `json:"languageCode"`
+	Kind           string `json:"kind"`
+	IsTranslatable bool   `json:"isTranslatable"`
+}
+
+type Microformat struct {
+	PlayerMicroformatRenderer struct {
+		PublishDate        string   `json:"publishDate"`
+		UploadDate         string   `json:"uploadDate"`
+		Category           string   `json:"category"`
+		IsFamilySafe       bool     `json:"isFamilySafe"`
+		AvailableCountries []string `json:"availableCountries"`
+	} `json:"playerMicroformatRenderer"`
+}
@@ -0,0 +1,49 @@
+package models
+
+// PlaylistData is the ytInitialData object embedded in YouTube playlist pages,
+// only the title and owner of the playlist are modeled.
+// They are in the header, and in the sidebar for the web client.
+type PlaylistData struct {
+	Header struct {
+		PlaylistHeaderRenderer struct {
+			Title     Text `json:"title"`
+			OwnerText Text `json:"ownerText"`
+		} `json:"playlistHeaderRenderer"`
+	} `json:"header"`
+	Sidebar struct {
+		PlaylistSidebarRenderer struct {
+			Items []struct {
+				PlaylistSidebarPrimaryInfoRenderer struct {
+					Title Text `json:"title"`
+				} `json:"playlistSidebarPrimaryInfoRenderer"`
+				PlaylistSidebarSecondaryInfoRenderer struct {
+					VideoOwner struct {
+						VideoOwnerRenderer struct {
+							Title Text `json:"title"`
+						} `json:"videoOwnerRenderer"`
+					} `json:"videoOwner"`
+				} `json:"playlistSidebarSecondaryInfoRenderer"`
+			} `json:"items"`
+		} `json:"playlistSidebarRenderer"`
+	} `json:"sidebar"`
+}
+
+// Title is the title of the sidebar, or of the header when there's no sidebar.
+func (d *PlaylistData) Title() string {
+	for _, item := range d.Sidebar.PlaylistSidebarRenderer.Items {
+		if title := item.PlaylistSidebarPrimaryInfoRenderer.Title.String(); title != "" {
+			return title
+		}
+	}
+	return d.Header.PlaylistHeaderRenderer.Title.String()
+}
+
+// Author is the owner of the sidebar, or of the header when there's no sidebar.
+func (d *PlaylistData) Author() string {
+	for _, item := range d.Sidebar.PlaylistSidebarRenderer.Items {
+		if author := item.PlaylistSidebarSecondaryInfoRenderer.VideoOwner.VideoOwnerRenderer.Title.String(); author != "" {
+			return author
+		}
+	}
+	return d.Header.PlaylistHeaderRenderer.OwnerText.String()
+}
@@ -3,7 +3,8 @@ package parser
 import (
 	"errors"
 	"net/url"
-	"ytdl/models"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
 )
 
 const audioQualityMedium string = "AUDIO_QUALITY_MEDIUM"
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+const playerResponseVar string = "ytInitialPlayerResponse"
+
+const initialDataVar string = "ytInitialData"
+
+// ErrorNoPlayerResponse the page doesn't embed ytInitialPlayerResponse, it's likely not a watch page.
+var ErrorNoPlayerResponse = errors.New("page does not contain a player response, check if the link leads to a youtube video")
+
+// ParsePlayerResponse finds `ytInitialPlayerResponse = {...};` in a watch page and decodes it.
+func ParsePlayerResponse(html string) (*models.PlayerResponse, error) {
+	object, ok := FindJSONObject(html, playerResponseVar)
+	if !ok {
+		return nil, ErrorNoPlayerResponse
+	}
+
+	var response models.PlayerResponse
+	if err := json.Unmarshal([]byte(object), &response); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", playerResponseVar, err)
+	}
+	return &response, nil
+}
+
+// ErrorNoPlaylistData the page doesn't embed ytInitialData, it's likely not a playlist page.
+var ErrorNoPlaylistData = errors.New("page does not contain playlist data, check if the link leads to a youtube playlist")
+
+// ParsePlaylistData finds `ytInitialData = {...};` in a playlist page and decodes it.
+func ParsePlaylistData(html string) (*models.PlaylistData, error) {
+	object, ok := FindJSONObject(html, initialDataVar)
+	if !ok {
+		return nil, ErrorNoPlaylistData
+	}
+
+	var data models.PlaylistData
+	if err := json.Unmarshal([]byte(object), &data); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", initialDataVar, err)
+	}
+	return &data, nil
+}
+
+// DirectFormats returns the formats of streamingData.formats that can be downloaded as they are:
+// they have a url, protected videos only have a signatureCipher, and a mimeType telling what they are.
+func DirectFormats(response *models.PlayerResponse) []models.PlayerFormat {
+	var formats []models.PlayerFormat
+	for _, format := range response.StreamingData.Formats {
+		if format.Url != "" && format.MimeType != "" {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// FindJSONObject returns the JSON object assigned to name in a page's inline scripts.
+// Braces inside of strings are skipped, so nested objects and text containing "};" are fine.
+func FindJSONObject(html string, name string) (string, bool) {
+	offset := 0
+	for {
+		idx := strings.Index(html[offset:], name)
+		if idx < 0 {
+			return "", false
+		}
+		offset += idx + len(name)
+
+		// the name has to be followed by `=` and the object, anything else is just a mention
+		rest := strings.TrimLeft(html[offset:], " \t\r\n")
+		if !strings.HasPrefix(rest, "=") {
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], " \t\r\n")
+		if !strings.HasPrefix(rest, "{") {
+			continue
+		}
+
+		end := matchBrace(rest)
+		if end < 0 {
+			return "", false
+		}
+		return rest[:end+1], true
+	}
+}
+
+// matchBrace returns the index of the brace closing the one s starts with, or -1.
+func matchBrace(s string) int {
+	depth := 0
+	inString := false
+	escaped := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+func parseFixture(t *testing.T, name string) *models.PlayerResponse {
+	t.Helper()
+	html, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := ParsePlayerResponse(string(html))
+	if err != nil {
+		t.Fatalf("ParsePlayerResponse(%s): %v", name, err)
+	}
+	return response
+}
+
+func TestParsePlayerResponse(t *testing.T) {
+	response := parseFixture(t, "watch_ok.html")
+
+	details := response.VideoDetails
+	if details.VideoId != "dQw4w9WgXcQ" || details.Title != "Braces {in} titles};" || details.Author != "Rick Astley" || details.LengthSeconds != "212" {
+		t.Errorf("videoDetails = %+v", details)
+	}
+	// braces and quotes inside strings don't end the object
+	wantDescription := `Quotes "inside" strings, a lone } and {\ plus an escaped backslash \\" and a closing "};" too.`
+	if details.ShortDescription != wantDescription {
+		t.Errorf("shortDescription = %q, want %q", details.ShortDescription, wantDescription)
+	}
+
+	if response.PlayabilityStatus.Status != "OK" || !response.PlayabilityStatus.PlayableInEmbed {
+		t.Errorf("playabilityStatus = %+v, want OK", response.PlayabilityStatus)
+	}
+	if n := len(response.StreamingData.Formats); n != 3 {
+		t.Errorf("found %d formats, want 3", n)
+	}
+	if adaptive := response.StreamingData.AdaptiveFormats; len(adaptive) != 1 || adaptive[0].Itag != 140 || adaptive[0].FormatInfo().ContentLength != 3433253 {
+		t.Errorf("adaptiveFormats = %+v, want itag 140 of 3433253 bytes", adaptive)
+	}
+	tracks := response.Captions.PlayerCaptionsTracklistRenderer.CaptionTracks
+	if len(tracks) != 1 || tracks[0].LanguageCode != "en" || tracks[0].Name.String() != "English" {
+		t.Errorf("captionTracks = %+v, want the English track", tracks)
+	}
+	microformat := response.Microformat.PlayerMicroformatRenderer
+	if microformat.PublishDate != "2009-10-24" || len(microformat.AvailableCountries) != 3 {
+		t.Errorf("microformat = %+v", microformat)
+	}
+}
+
+func TestDirectFormats(t *testing.T) {
+	response := parseFixture(t, "watch_ok.html")
+
+	// 22 only has a signatureCipher and 17 has no mimeType
+	formats := DirectFormats(response)
+	if len(formats) != 1 || formats[0].Itag != 18 {
+		t.Fatalf("DirectFormats = %+v, want only itag 18", formats)
+	}
+	if formats[0].MimeType != `video/mp4; codecs="avc1.42001E, mp4a.40.2"` || formats[0].Url == "" {
+		t.Errorf("format 18 = %+v", formats[0])
+	}
+
+	if formats := DirectFormats(parseFixture(t, "watch_private.html")); len(formats) != 0 {
+		t.Errorf("DirectFormats of a private video = %+v, want none", formats)
+	}
+}
+
+func TestParsePlayabilityStatus(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		videoID   string
+		status    string
+		reason    string
+		subreason string
+	}{
+		{"watch_private.html", "pr1vat3v1d0", "LOGIN_REQUIRED", "This video is private", "If the owner of this video has granted you access, please sign in."},
+		{"watch_age_restricted.html", "ag3r3str1ct", "LOGIN_REQUIRED", "Sign in to confirm your age", "This video may be inappropriate for some users."},
+		{"watch_geo_blocked.html", "g30bl0ck3d1", "UNPLAYABLE", "Video unavailable", "The uploader has not made this video available in your country"},
+		{"watch_live.html", "l1v3str3am1", "LIVE_STREAM_OFFLINE", "This live event will begin in 3 hours.", ""},
+	}
+	for _, tt := range tests {
+		response := parseFixture(t, tt.fixture)
+		status := response.PlayabilityStatus
+		renderer := status.ErrorScreen.PlayerErrorMessageRenderer
+		if response.VideoDetails.VideoId != tt.videoID || status.Status != tt.status || status.Reason != tt.reason || renderer.Subreason.String() != tt.subreason {
+			t.Errorf("%s: video %q, status %q, reason %q, subreason %q, want %q, %q, %q, %q", tt.fixture,
+				response.VideoDetails.VideoId, status.Status, status.Reason, renderer.Subreason.String(),
+				tt.videoID, tt.status, tt.reason, tt.subreason)
+		}
+		if tt.subreason != "" && renderer.Reason.String() != tt.reason {
+			t.Errorf("%s: errorScreen reason %q, want %q", tt.fixture, renderer.Reason.String(), tt.reason)
+		}
+	}
+}
+
+func TestParsePlayerResponseMissing(t *testing.T) {
+	html, err := os.ReadFile(filepath.Join("testdata", "consent.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParsePlayerResponse(string(html)); !errors.Is(err, ErrorNoPlayerResponse) {
+		t.Errorf("error = %v, want ErrorNoPlayerResponse", err)
+	}
+
+	// an object that never closes
+	if _, err := ParsePlayerResponse(`<script>var ytInitialPlayerResponse = {"a": {"b": "}"};</script>`); !errors.Is(err, ErrorNoPlayerResponse) {
+		t.Errorf("error = %v, want ErrorNoPlayerResponse", err)
+	}
+}
+
+func TestFindJSONObject(t *testing.T) {
+	tests := []struct {
+		html   string
+		object string
+		ok     bool
+	}{
+		{`var x = {"a": {"b": {}}};`, `{"a": {"b": {}}}`, true},
+		{`x={"s": "{{{"}` + "\n", `{"s": "{{{"}`, true},
+		{`x = {"s": "\"}"} ; y = {}`, `{"s": "\"}"}`, true},
+		// mentions of the name without an assignment are skipped
+		{`if (x) {}; x == 1; x = {"n": 1}`, `{"n": 1}`, true},
+		{`x = null; x = {}`, `{}`, true},
+		{`x = {"open": [`, ``, false},
+		{`y = {}`, ``, false},
+	}
+	for _, tt := range tests {
+		object, ok := FindJSONObject(tt.html, "x")
+		if object != tt.object || ok != tt.ok {
+			t.Errorf("FindJSONObject(%q) = %q, %v, want %q, %v", tt.html, object, ok, tt.object, tt.ok)
+		}
+	}
+}
+
+func TestParsePlaylistData(t *testing.T) {
+	header := `"header": {"playlistHeaderRenderer": {"title": {"simpleText": "Header title"}, "ownerText": {"runs": [{"text": "Header owner"}]}}}`
+	sidebar := `"sidebar": {"playlistSidebarRenderer": {"items": [
+		{"playlistSidebarPrimaryInfoRenderer": {"title": {"runs": [{"text": "P"}, {"text": "L"}]}}},
+		{"playlistSidebarSecondaryInfoRenderer": {"videoOwner": {"videoOwnerRenderer": {"title": {"runs": [{"text": "AB"}]}}}}}]}}`
+
+	tests := []struct {
+		data   string
+		title  string
+		author string
+	}{
+		{"{" + header + ", " + sidebar + "}", "PL", "AB"},
+		{"{" + header + "}", "Header title", "Header owner"},
+		{"{}", "", ""},
+	}
+	for _, tt := range tests {
+		data, err := ParsePlaylistData(`<script>var ytInitialData = ` + tt.data + `;</script>`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.Title() != tt.title || data.Author() != tt.author {
+			t.Errorf("title, author = %q, %q, want %q, %q", data.Title(), data.Author(), tt.title, tt.author)
+		}
+	}
+
+	if _, err := ParsePlaylistData(`<script>var ytInitialPlayerResponse = {};</script>`); !errors.Is(err, ErrorNoPlaylistData) {
+		t.Errorf("error = %v, want ErrorNoPlaylistData", err)
+	}
+}
@@ -0,0 +1,223 @@
+// Package ytdl embeds the downloader in other programs, the ytdl command is built on it.
+// Nothing is printed and nothing exits: outcomes come back as a Result and a channel of events.
+//
+//	client := ytdl.New(ytdl.Options{})
+//	result, events := client.Download(ctx, link, ytdl.DownloadOptions{OutputDir: dir})
+//	for event := range events {
+//		log.Println(event.Type, event.Title)
+//	}
+//	if err := result.Err(); err != nil {
+//		...
+//	}
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/downloader"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/postprocess"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/progress"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/rootpath"
+)
+
+// eventBuffer is how many events a download may get ahead of the reader of its channel.
+const eventBuffer = 64
+
+// Options configures a Client.
+type Options struct {
+	// HTTPClient sends every request, set its Jar for signed in downloads. http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// Log receives the human readable output of the command line tool, it's discarded when nil.
+	Log io.Writer
+	// Progress is told about the stages and bytes of every download, nothing is reported when nil.
+	Progress progress.Reporter
+	// SkipDuplicates downloads a video only once per Client, however many links point to it.
+	SkipDuplicates bool
+}
+
+// DownloadOptions controls a single Download or Sync.
+type DownloadOptions struct {
+	// OutputDir receives the files, playlists get a folder in it. It's created when missing.
+	OutputDir string
+	// VideoOnly downloads only the linked video even if the link points into a playlist.
+	VideoOnly     bool
+	IncludeAuthor bool
+	// Format picks the stream: an itag ("18"), a quality label ("720p") or a quality ("medium").
+	// When empty the first format with audio channels is used.
+	Format string
+	// AudioOnly downloads the best audio only stream instead of a video.
+	AudioOnly bool
+	// Template overrides the file name using {title}, {author}, {id} and {index}.
+	Template string
+	// WriteInfoJSON writes <name>.info.json with the video metadata and the chosen format.
+	WriteInfoJSON bool
+	// WriteDescription writes the video description to <name>.description.
+	WriteDescription bool
+	// WritePlaylistMetafiles writes playlist.info.json into the playlist folder.
+	WritePlaylistMetafiles bool
+	// OnConflict handles output files that already exist, downloader.ConflictSkip when empty.
+	OnConflict downloader.ConflictPolicy
+	// FilenameProfile restricts file and folder names, the host OS rules apply when empty.
+	FilenameProfile rootpath.Profile
+	// PostProcess runs on every saved file in order, File.Path is where the file ends up.
+	// The steps send their requests with Options.HTTPClient and log to Options.Log.
+	PostProcess postprocess.Chain
+}
+
+// Event describes the state of a single video, see downloader.Event.
+type Event = downloader.Event
+
+// EventType tells what happened to a video.
+type EventType = downloader.EventType
+
+const (
+	EventQueued   = downloader.EventQueued
+	EventMetadata = downloader.EventMetadata
+	EventProgress = downloader.EventProgress
+	EventDone     = downloader.EventDone
+	EventSkipped  = downloader.EventSkipped
+	EventError    = downloader.EventError
+)
+
+// File is a video saved, or found already saved, by a download.
+type File struct {
+	Link    string
+	VideoID string
+	Title   string
+	Path    string
+	Bytes   int64
+	// Skipped is set when the file was already there and nothing was downloaded.
+	Skipped bool
+}
+
+// Result is the outcome of a Download or Sync, its fields are set once the events channel is closed.
+type Result struct {
+	Link   string
+	Files  []File
+	Errors []error
+	done   chan struct{}
+}
+
+// Wait blocks until the download is over and returns Err.
+func (r *Result) Wait() error {
+	<-r.done
+	return r.Err()
+}
+
+// Err joins the errors of the download, nil when every video was saved.
+func (r *Result) Err() error {
+	return errors.Join(r.Errors...)
+}
+
+// Client downloads links with the same HTTP client and settings.
+// It's safe for concurrent use.
+type Client struct {
+	opts     Options
+	registry *extractor.Registry
+	dedup    *downloader.Dedup
+}
+
+// New returns a Client using opts.
+func New(opts Options) *Client {
+	client := &Client{opts: opts, registry: extractor.NewDefaultRegistry(opts.HTTPClient)}
+	if opts.SkipDuplicates {
+		client.dedup = downloader.NewDedup()
+	}
+	return client
+}
+
+// Download saves the video or playlist behind link into opts.OutputDir in the background.
+// The events of every video are sent on the returned channel, which must be read
+// until it's closed; the Result is complete by then.
+// Canceling ctx stops the download and removes its partial files.
+func (c *Client) Download(ctx context.Context, link string, opts DownloadOptions) (*Result, <-chan Event) {
+	return c.run(link, opts, func(dopts downloader.Options) []error {
+		return downloader.DownloadLink(ctx, link, dopts)
+	})
+}
+
+// SyncOptions controls what Sync does besides downloading new entries.
+type SyncOptions = downloader.SyncOptions
+
+// Sync makes the folder of the playlist behind link in opts.OutputDir match the playlist,
+// see downloader.SyncPlaylist. Events and the Result work as with Download.
+func (c *Client) Sync(ctx context.Context, link string, opts DownloadOptions, syncOpts SyncOptions) (*Result, <-chan Event) {
+	return c.run(link, opts, func(dopts downloader.Options) []error {
+		return downloader.SyncPlaylist(ctx, link, dopts, syncOpts)
+	})
+}
+
+// Info fetches the metadata of link without downloading anything, only opts.VideoOnly is used.
+// Exactly one of the returned infos is set when err is nil.
+func (c *Client) Info(ctx context.Context, link string, opts DownloadOptions) (*models.VideoInfo, *models.PlaylistInfo, error) {
+	return downloader.FetchInfo(ctx, link, c.options(opts))
+}
+
+// run calls download in the background with the downloader options of opts,
+// collecting its events into the Result.
+func (c *Client) run(link string, opts DownloadOptions, download func(downloader.Options) []error) (*Result, <-chan Event) {
+	result := &Result{Link: link, done: make(chan struct{})}
+	events := make(chan Event, eventBuffer)
+
+	dopts := c.options(opts)
+	dopts.OnEvent = func(event Event) {
+		switch event.Type {
+		case EventDone, EventSkipped:
+			result.Files = append(result.Files, File{
+				Link:    event.Link,
+				VideoID: event.VideoID,
+				Title:   event.Title,
+				Path:    event.Path,
+				Bytes:   event.Bytes,
+				Skipped: event.Type == EventSkipped,
+			})
+		}
+		events <- event
+	}
+
+	go func() {
+		if err := rootpath.CreateDirectoryIfNotExists(opts.OutputDir); err != nil {
+			result.Errors = []error{err}
+		} else {
+			result.Errors = download(dopts)
+		}
+		close(result.done)
+		close(events)
+	}()
+	return result, events
+}
+
+func (c *Client) options(opts DownloadOptions) downloader.Options {
+	log := c.opts.Log
+	if log == nil {
+		log = io.Discard
+	}
+
+	return downloader.Options{
+		OutputDir:     opts.OutputDir,
+		VideoOnly:     opts.VideoOnly,
+		IncludeAuthor: opts.IncludeAuthor,
+		Format:        opts.Format,
+		AudioOnly:     opts.AudioOnly,
+		Template:      opts.Template,
+
+		WriteInfoJSON:          opts.WriteInfoJSON,
+		WriteDescription:       opts.WriteDescription,
+		WritePlaylistMetafiles: opts.WritePlaylistMetafiles,
+
+		OnConflict:      opts.OnConflict,
+		FilenameProfile: opts.FilenameProfile,
+		PostProcess:     opts.PostProcess,
+
+		HTTPClient: c.opts.HTTPClient,
+		Log:        log,
+		Progress:   c.opts.Progress,
+		Registry:   c.registry,
+		Dedup:      c.dedup,
+	}
+}
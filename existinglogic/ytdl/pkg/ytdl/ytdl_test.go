@@ -0,0 +1,150 @@
+package ytdl_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/pkg/ytdl"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/testserver"
+)
+
+// download runs a download to the end and fails the test on errors.
+func download(t *testing.T, client *ytdl.Client, link string, opts ytdl.DownloadOptions) *ytdl.Result {
+	t.Helper()
+	result, events := client.Download(context.Background(), link, opts)
+	for range events {
+	}
+	if err := result.Err(); err != nil {
+		t.Fatalf("Download(%s): %v", link, err)
+	}
+	return result
+}
+
+// checkFiles compares the files under dir, by path relative to it, with their wanted content.
+func checkFiles(t *testing.T, dir string, want map[string][]byte) {
+	t.Helper()
+	got := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		got[filepath.ToSlash(rel)], err = os.ReadFile(path)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for name := range got {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(got) != len(want) {
+		t.Errorf("files %q, want %d files", names, len(want))
+	}
+	for name, content := range want {
+		data, ok := got[name]
+		if !ok {
+			t.Errorf("%s is missing, found %q", name, names)
+		} else if !bytes.Equal(data, content) {
+			t.Errorf("%s has %d bytes, want %d", name, len(data), len(content))
+		}
+	}
+}
+
+func TestDownloadVideo(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	media := []byte(strings.Repeat("first video\n", 5000))
+	video := testserver.Video{ID: "aaaaaaaaaaa", Title: "First video", Author: "Ann", Duration: 61, Media: media}
+	srv.AddVideo(video)
+	client := ytdl.New(ytdl.Options{HTTPClient: srv.Client()})
+
+	dir := t.TempDir()
+	result := download(t, client, "https://youtu.be/aaaaaaaaaaa", ytdl.DownloadOptions{OutputDir: dir})
+
+	checkFiles(t, dir, map[string][]byte{"First video.mp4": media})
+	if len(result.Files) != 1 || result.Files[0].Path != filepath.Join(dir, "First video.mp4") ||
+		result.Files[0].Bytes != int64(len(media)) || result.Files[0].VideoID != video.ID {
+		t.Errorf("files = %+v", result.Files)
+	}
+
+	// a second download keeps the complete file
+	result = download(t, client, "https://www.youtube.com/watch?v=aaaaaaaaaaa", ytdl.DownloadOptions{OutputDir: dir})
+	if len(result.Files) != 1 || !result.Files[0].Skipped {
+		t.Errorf("files = %+v, want the file skipped", result.Files)
+	}
+	checkFiles(t, dir, map[string][]byte{"First video.mp4": media})
+}
+
+func TestDownloadPlaylist(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	// entries come in pages of 2
+	srv.PageSize = 2
+	want := map[string][]byte{}
+	var ids []string
+	for i, title := range []string{"One", "Two", "Three"} {
+		id := strings.Repeat(string(rune('a'+i)), 11)
+		media := []byte(strings.Repeat(title, 1000+i))
+		srv.AddVideo(testserver.Video{ID: id, Title: title, Author: "Ann", Media: media})
+		ids = append(ids, id)
+		want["PL - Ann/"+title+" - Ann.mp4"] = media
+	}
+	// kkdai/youtube parses a 2 characters title as empty
+	srv.AddPlaylist(testserver.Playlist{ID: "PLtest0123456789", Title: "PL", Author: "Ann", VideoIDs: ids})
+	client := ytdl.New(ytdl.Options{HTTPClient: srv.Client()})
+
+	dir := t.TempDir()
+	result := download(t, client, "https://www.youtube.com/playlist?list=PLtest0123456789",
+		ytdl.DownloadOptions{OutputDir: dir, IncludeAuthor: true})
+	if len(result.Files) != 3 {
+		t.Errorf("files = %+v, want 3", result.Files)
+	}
+	checkFiles(t, dir, want)
+}
+
+// rangeCounter counts the requests of media ranges going through it.
+type rangeCounter struct {
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (c *rangeCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if value := req.URL.Query().Get("range"); value != "" {
+		c.mu.Lock()
+		c.ranges = append(c.ranges, value)
+		c.mu.Unlock()
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestDownloadRanged(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	// larger than the 10 MB chunks media is fetched in
+	media := make([]byte, 10<<20+12345)
+	for i := range media {
+		media[i] = byte(i % 251)
+	}
+	srv.AddVideo(testserver.Video{ID: "rrrrrrrrrrr", Title: "Long", Media: media})
+	counter := &rangeCounter{}
+	client := ytdl.New(ytdl.Options{HTTPClient: &http.Client{Transport: srv.Transport(counter)}})
+
+	dir := t.TempDir()
+	download(t, client, "https://youtu.be/rrrrrrrrrrr", ytdl.DownloadOptions{OutputDir: dir})
+
+	checkFiles(t, dir, map[string][]byte{"Long.mp4": media})
+	if len(counter.ranges) < 2 {
+		t.Errorf("media fetched with ranges %q, want at least 2 chunks", counter.ranges)
+	}
+}
@@ -0,0 +1,145 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Exec runs a shell command on the file, e.g. "notify-send {title}" or "cp {path} /mnt/backup".
+// The template may use:
+//   - {path}   the file
+//   - {dir}    the folder of the file
+//   - {name}   the file name
+//   - {title}  video title
+//   - {author} video author
+//   - {id}     video id
+//   - {url}    video link
+//   - {index}  position in the playlist (empty for single videos)
+//
+// The values are passed in the environment as YTDL_PATH, YTDL_DIR... and a field stands
+// for its quoted variable, {title} is "$YTDL_TITLE" ("!YTDL_TITLE!" on Windows), so the shell
+// never reads a value as code. Fields can't be quoted again, write "$YTDL_TITLE" to use
+// a value inside a longer quoted string.
+// The path is appended when the template doesn't use {path}.
+// The output of the command goes to the log of the item.
+type Exec struct {
+	Template string
+}
+
+// fields are the names of the values a template may use, see Exec.
+var fields = []string{"path", "dir", "name", "title", "author", "id", "url", "index"}
+
+func (p *Exec) Name() string {
+	return "exec"
+}
+
+// check refuses fields inside quotes, which would be quoted twice.
+func (p *Exec) check() error {
+	windows := runtime.GOOS == "windows"
+	var quote byte
+	escaped := false
+	for i := 0; i < len(p.Template); i++ {
+		c := p.Template[i]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quote != '\'' && !windows:
+			escaped = true
+		case quote == 0 && (c == '"' || c == '\'' && !windows):
+			quote = c
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0 && c == '{':
+			for _, field := range fields {
+				if strings.HasPrefix(p.Template[i:], "{"+field+"}") {
+					return fmt.Errorf("{%s} is quoted already, remove the quotes around it or write %s", field, shellVariable(field))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Exec) Process(ctx context.Context, item *Item) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	template := p.Template
+	if !strings.Contains(template, "{path}") {
+		template += " {path}"
+	}
+	command := expand(template, item, nil)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		// delayed expansion happens once the command is parsed, values can't add commands
+		cmd = exec.CommandContext(ctx, "cmd", "/V:ON", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = os.Environ()
+	for field, value := range fieldValues(item) {
+		cmd.Env = append(cmd.Env, environmentName(field)+"="+value)
+	}
+	cmd.Stdout = item.log()
+	cmd.Stderr = item.log()
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("'%s': %w", command, err)
+	}
+	return nil
+}
+
+// fieldValues returns the values of the fields for item.
+func fieldValues(item *Item) map[string]string {
+	index := ""
+	if item.Index > 0 {
+		index = strconv.Itoa(item.Index)
+	}
+	return map[string]string{
+		"path":   item.Path,
+		"dir":    filepath.Dir(item.Path),
+		"name":   filepath.Base(item.Path),
+		"title":  item.Video.Title,
+		"author": item.Video.Author,
+		"id":     item.Video.ID,
+		"url":    item.Video.Url,
+		"index":  index,
+	}
+}
+
+// expand replaces the fields of template with the values of item passed through escape,
+// or with their quoted shell variables when escape is nil.
+func expand(template string, item *Item, escape func(string) string) string {
+	values := fieldValues(item)
+	var pairs []string
+	for _, field := range fields {
+		replacement := shellVariable(field)
+		if escape != nil {
+			replacement = escape(values[field])
+		}
+		pairs = append(pairs, "{"+field+"}", replacement)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// environmentName is the variable holding the value of field, e.g. YTDL_TITLE.
+func environmentName(field string) string {
+	return "YTDL_" + strings.ToUpper(field)
+}
+
+// shellVariable is the quoted reference to the variable of field in the shell Exec runs.
+func shellVariable(field string) string {
+	if runtime.GOOS == "windows" {
+		return `"!` + environmentName(field) + `!"`
+	}
+	return `"$` + environmentName(field) + `"`
+}
@@ -0,0 +1,222 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// audioCodecs are the ffmpeg encoder arguments of the formats ExtractAudio writes.
+var audioCodecs = map[string][]string{
+	"mp3":  {"-c:a", "libmp3lame", "-q:a", "2"},
+	"m4a":  {"-c:a", "aac", "-b:a", "192k"},
+	"opus": {"-c:a", "libopus", "-b:a", "128k"},
+	"flac": {"-c:a", "flac"},
+	"wav":  {"-c:a", "pcm_s16le"},
+}
+
+// ExtractAudio converts the file to an audio file next to it, with the same name.
+type ExtractAudio struct {
+	// Format is the audio file extension: mp3, m4a, opus, flac or wav. mp3 when empty.
+	Format string
+	// Keep keeps the original file, it's removed otherwise.
+	Keep bool
+}
+
+func (p *ExtractAudio) Name() string {
+	return "extract-audio"
+}
+
+func (p *ExtractAudio) codec() ([]string, error) {
+	format := p.format()
+	codec, ok := audioCodecs[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown audio format '%s', expected mp3, m4a, opus, flac or wav", format)
+	}
+	return codec, nil
+}
+
+func (p *ExtractAudio) format() string {
+	if p.Format == "" {
+		return "mp3"
+	}
+	return strings.ToLower(p.Format)
+}
+
+func (p *ExtractAudio) Process(ctx context.Context, item *Item) error {
+	codec, err := p.codec()
+	if err != nil {
+		return err
+	}
+	output := replaceExt(item.Path, "."+p.format())
+	// already an audio file of that format
+	if output == item.Path {
+		return nil
+	}
+
+	args := append([]string{"-i", item.Path, "-vn"}, codec...)
+	output, err = convert(ctx, item, output, nil, args...)
+	if err != nil {
+		return err
+	}
+	if !p.Keep {
+		if err := os.Remove(item.Path); err != nil {
+			return err
+		}
+	}
+	item.Path = output
+	return nil
+}
+
+// remuxContainers are the containers Parse accepts for Remux.
+var remuxContainers = map[string]bool{"mp4": true, "mkv": true, "mov": true, "webm": true}
+
+// Remux copies the streams of the file into another container without re-encoding them,
+// the original file is replaced.
+type Remux struct {
+	// Container is the extension of the new file, e.g. mkv or mp4.
+	Container string
+}
+
+func (p *Remux) Name() string {
+	return "remux"
+}
+
+func (p *Remux) Process(ctx context.Context, item *Item) error {
+	output := replaceExt(item.Path, "."+strings.ToLower(p.Container))
+	if output == item.Path {
+		return nil
+	}
+
+	output, err := convert(ctx, item, output, nil, "-i", item.Path, "-map", "0", "-c", "copy")
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(item.Path); err != nil {
+		return err
+	}
+	item.Path = output
+	return nil
+}
+
+// Tag writes the title, author, date, link and playlist position of the video into the file.
+type Tag struct{}
+
+func (p *Tag) Name() string {
+	return "tag"
+}
+
+func (p *Tag) Process(ctx context.Context, item *Item) error {
+	tags := []string{
+		"title=" + item.Video.Title,
+		"artist=" + item.Video.Author,
+		"date=" + item.Video.PublishDate,
+		"comment=" + item.Video.Url,
+		"description=" + item.Video.Description,
+	}
+	if item.Index > 0 {
+		tags = append(tags, "track="+strconv.Itoa(item.Index))
+	}
+
+	args := []string{"-i", item.Path, "-map", "0", "-c", "copy"}
+	if isExt(item.Path, ".mp3") {
+		args = append(args, "-id3v2_version", "3")
+	}
+	for _, tag := range tags {
+		args = append(args, "-metadata", tag)
+	}
+	return rewrite(ctx, item, nil, args...)
+}
+
+// EmbedThumbnail fetches the largest thumbnail of the video and embeds it as cover art.
+// mp3, m4a, mp4, mov and mkv files are supported.
+type EmbedThumbnail struct{}
+
+func (p *EmbedThumbnail) Name() string {
+	return "embed-thumbnail"
+}
+
+func (p *EmbedThumbnail) Process(ctx context.Context, item *Item) error {
+	var link string
+	var width uint
+	for _, thumbnail := range item.Video.Thumbnails {
+		if link == "" || thumbnail.Width > width {
+			link, width = thumbnail.Url, thumbnail.Width
+		}
+	}
+	if link == "" {
+		fmt.Fprintf(item.log(), "\tNo thumbnail to embed into '%s'\n", item.Path)
+		return nil
+	}
+
+	thumbnail := replaceExt(item.Path, ".thumbnail.jpg")
+	if err := fetch(ctx, item.client(), link, thumbnail); err != nil {
+		return err
+	}
+	defer os.Remove(thumbnail)
+
+	switch {
+	case isExt(item.Path, ".mp3"):
+		return rewrite(ctx, item, nil,
+			"-i", item.Path, "-i", thumbnail, "-map", "0:a", "-map", "1", "-c", "copy",
+			"-id3v2_version", "3", "-metadata:s:v", "title=Album cover", "-metadata:s:v", "comment=Cover (front)",
+		)
+	case isExt(item.Path, ".m4a", ".mp4", ".mov"):
+		// the cover is the first output stream so its disposition doesn't depend on the streams of the file
+		return rewrite(ctx, item, nil,
+			"-i", item.Path, "-i", thumbnail, "-map", "1", "-map", "0", "-c", "copy",
+			"-disposition:0", "attached_pic",
+		)
+	case isExt(item.Path, ".mkv"):
+		return rewrite(ctx, item, nil,
+			"-i", item.Path, "-map", "0", "-c", "copy",
+			"-attach", thumbnail, "-metadata:s:t", "mimetype=image/jpeg", "-metadata:s:t", "filename=cover.jpg",
+		)
+	default:
+		return fmt.Errorf("can't embed a thumbnail into %s files", filepath.Ext(item.Path))
+	}
+}
+
+// fetch saves the body of link to path.
+func fetch(ctx context.Context, client *http.Client, link, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching '%s': %s", link, resp.Status)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+func isExt(path string, exts ...string) bool {
+	ext := filepath.Ext(path)
+	for _, e := range exts {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/rootpath"
+)
+
+// ErrorDestinationExists a step would replace a file.
+type ErrorDestinationExists struct {
+	Path string
+}
+
+func (e *ErrorDestinationExists) Error() string {
+	return fmt.Sprintf("'%s' already exists", e.Path)
+}
+
+// Move moves the file into another folder, e.g. a media library.
+// Dir may use the fields of Exec, e.g. "~/Music/{author}", they are made safe for file names.
+// Moving across filesystems copies the file and removes the original.
+// An existing destination is handled by Item.Output, the file is removed when the existing one is kept.
+type Move struct {
+	Dir string
+	// Filename renames the file, it keeps its name when empty.
+	Filename string
+}
+
+func (p *Move) Name() string {
+	return "move"
+}
+
+func (p *Move) Process(ctx context.Context, item *Item) error {
+	dir := expand(p.Dir, item, folderName)
+	if home, err := os.UserHomeDir(); err == nil && (dir == "~" || len(dir) > 1 && dir[0] == '~' && os.IsPathSeparator(dir[1])) {
+		dir = filepath.Join(home, dir[1:])
+	}
+	name := p.Filename
+	if name == "" {
+		name = filepath.Base(item.Path)
+	}
+	dst, keep, err := item.output(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	if keep {
+		fmt.Fprintf(item.log(), "\t'%s' already exists, keeping it\n", dst)
+		if err := os.Remove(item.Path); err != nil {
+			return err
+		}
+		item.Path = dst
+		return nil
+	}
+	if err := rootpath.CreateDirectoryIfNotExists(dir); err != nil {
+		return err
+	}
+	if err := moveFile(item.Path, dst); err != nil {
+		return err
+	}
+	item.Path = dst
+	return nil
+}
+
+// folderName makes a field value a single folder name, slashes included.
+func folderName(value string) string {
+	if runtime.GOOS == "windows" {
+		return rootpath.SanitizeFilename(value, rootpath.ProfileWindows)
+	}
+	return rootpath.SanitizeFilename(value, rootpath.ProfilePosix)
+}
+
+// moveFile renames src to dst, copying it when they are on different filesystems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
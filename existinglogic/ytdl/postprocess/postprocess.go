@@ -0,0 +1,305 @@
+// Package postprocess runs steps on a file once it's downloaded: converting it with ffmpeg,
+// tagging it, moving it into a library, running a command or calling a webhook.
+// Steps run in order, each one gets the file left by the previous one.
+package postprocess
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+// FFmpeg is the ffmpeg binary run by the converting steps, looked up in the PATH.
+var FFmpeg = "ffmpeg"
+
+// Item is a downloaded file going through the chain.
+type Item struct {
+	// Path is the file, steps converting or moving it point it to the new file.
+	Path   string            `json:"path"`
+	Video  models.VideoInfo  `json:"video"`
+	Format models.FormatInfo `json:"format"`
+	// Index is the position in the playlist, 0 for single videos.
+	Index int `json:"index,omitempty"`
+
+	// Client sends the requests of the steps, http.DefaultClient when nil.
+	Client *http.Client `json:"-"`
+	// Log receives the output of the steps, it's discarded when nil.
+	Log io.Writer `json:"-"`
+	// Stage is told what long steps are doing, e.g. "recoding 40%".
+	Stage func(stage string) `json:"-"`
+	// Output decides what happens when a step would write a file that already exists:
+	// it returns the path to write instead, or keep to take the existing file as the result.
+	// Steps fail with ErrorDestinationExists when nil.
+	Output func(path string) (dst string, keep bool, err error) `json:"-"`
+}
+
+func (item *Item) client() *http.Client {
+	if item.Client == nil {
+		return http.DefaultClient
+	}
+	return item.Client
+}
+
+// output returns where a step writes path, see Item.Output.
+func (item *Item) output(path string) (string, bool, error) {
+	if item.Output != nil {
+		return item.Output(path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", false, &ErrorDestinationExists{path}
+	}
+	return path, false, nil
+}
+
+func (item *Item) log() io.Writer {
+	if item.Log == nil {
+		return io.Discard
+	}
+	return item.Log
+}
+
+// ffmpegProgress returns a callback for ffmpegRun reporting every 10% of the video done
+// by ffmpeg to item.Stage, nil when there is nothing to report to or no duration to compare to.
+func (item *Item) ffmpegProgress(action string) func(seconds float64) {
+	duration := item.Video.Duration
+	if item.Stage == nil || duration <= 0 {
+		return nil
+	}
+
+	last := -10
+	return func(seconds float64) {
+		percent := min(int(seconds/duration*100), 100)
+		if percent/10 > last/10 {
+			last = percent
+			item.Stage(fmt.Sprintf("%s %d%%", action, percent))
+		}
+	}
+}
+
+// Processor is a single step of a Chain.
+type Processor interface {
+	// Name identifies the step in errors and in the .info.json sidecar.
+	Name() string
+	// Process works on item.Path, setting it when the file is replaced or moved.
+	Process(ctx context.Context, item *Item) error
+}
+
+// ErrorStep a step of the chain failed, the file is left as the previous step made it.
+type ErrorStep struct {
+	Step string
+	Path string
+	Err  error
+}
+
+func (e *ErrorStep) Error() string {
+	return fmt.Sprintf("post-processing '%s': %s: %v", e.Path, e.Step, e.Err)
+}
+
+func (e *ErrorStep) Unwrap() error {
+	return e.Err
+}
+
+// Chain is an ordered list of steps.
+type Chain []Processor
+
+// Run runs the steps on item one after the other and stops at the first failure.
+func (c Chain) Run(ctx context.Context, item *Item) error {
+	for _, p := range c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := p.Process(ctx, item); err != nil {
+			return &ErrorStep{Step: p.Name(), Path: item.Path, Err: err}
+		}
+	}
+	return nil
+}
+
+// Names lists the steps in order.
+func (c Chain) Names() []string {
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.Name()
+	}
+	return names
+}
+
+// Parse builds a step from its command line form, "<name>" or "<name>:<argument>":
+//   - extract-audio[:<mp3|m4a|opus|flac|wav>]
+//   - remux:<mp4|mkv|mov|webm>
+//   - recode:<h264|h265|vp9|av1>[:crf=<n>][:<resolution>], e.g. recode:h265:crf=26:720p
+//   - embed-thumbnail
+//   - tag
+//   - exec:<command template>
+//   - move:<directory template>
+//   - webhook:<url>
+func Parse(spec string) (Processor, error) {
+	name, arg, _ := strings.Cut(spec, ":")
+	needArg := func(what string) error {
+		if arg == "" {
+			return fmt.Errorf("%s needs a %s, e.g. %s:<%s>", name, what, name, what)
+		}
+		return nil
+	}
+
+	switch name {
+	case "extract-audio":
+		p := &ExtractAudio{Format: arg}
+		if _, err := p.codec(); err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "remux":
+		if err := needArg("container"); err != nil {
+			return nil, err
+		}
+		if !remuxContainers[strings.ToLower(arg)] {
+			return nil, fmt.Errorf("can't remux to '%s', expected mp4, mkv, mov or webm", arg)
+		}
+		return &Remux{Container: arg}, nil
+	case "recode":
+		if err := needArg("codec"); err != nil {
+			return nil, err
+		}
+		return parseRecode(arg)
+	case "embed-thumbnail":
+		return &EmbedThumbnail{}, nil
+	case "tag":
+		return &Tag{}, nil
+	case "exec":
+		if err := needArg("command"); err != nil {
+			return nil, err
+		}
+		p := &Exec{Template: arg}
+		if err := p.check(); err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "move":
+		if err := needArg("directory"); err != nil {
+			return nil, err
+		}
+		return &Move{Dir: arg}, nil
+	case "webhook":
+		if err := needArg("url"); err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(arg); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("webhook needs an http or https url, got '%s'", arg)
+		}
+		return &Webhook{URL: arg}, nil
+	default:
+		return nil, fmt.Errorf("unknown post-processor '%s', expected extract-audio, remux, recode, embed-thumbnail, tag, exec, move or webhook", name)
+	}
+}
+
+// ParseChain parses every spec with Parse, keeping their order.
+func ParseChain(specs []string) (Chain, error) {
+	var chain Chain
+	for _, spec := range specs {
+		p, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, p)
+	}
+	return chain, nil
+}
+
+// ffmpeg runs FFmpeg with args, its error output explains failures.
+// When progress isn't nil it's told how many seconds of the output are written.
+// Existing outputs are replaced, callers decide beforehand whether they may be.
+func ffmpeg(ctx context.Context, progress func(seconds float64), args ...string) error {
+	global := []string{"-y", "-nostdin", "-loglevel", "error"}
+	if progress != nil {
+		global = append(global, "-progress", "pipe:1", "-nostats")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, FFmpeg, append(global, args...)...)
+	cmd.Stderr = &stderr
+	err := func() error {
+		if progress == nil {
+			return cmd.Run()
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		// key=value lines, out_time_us is the position in the output in microseconds
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			value, ok := strings.CutPrefix(scanner.Text(), "out_time_us=")
+			if us, err := strconv.ParseInt(value, 10, 64); ok && err == nil && us >= 0 {
+				progress(float64(us) / 1e6)
+			}
+		}
+		return cmd.Wait()
+	}()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if reason := strings.TrimSpace(stderr.String()); reason != "" {
+			return fmt.Errorf("ffmpeg: %s", reason)
+		}
+		return fmt.Errorf("ffmpeg: %w", err)
+	}
+	return nil
+}
+
+// convert runs ffmpeg writing output, or where item.Output tells when output exists,
+// and returns the path of the new file. It's removed when ffmpeg fails.
+// A file kept by item.Output is returned as it is, without running ffmpeg.
+func convert(ctx context.Context, item *Item, output string, progress func(float64), args ...string) (string, error) {
+	output, keep, err := item.output(output)
+	if err != nil {
+		return "", err
+	}
+	if keep {
+		fmt.Fprintf(item.log(), "\t'%s' already exists, keeping it\n", output)
+		return output, nil
+	}
+
+	if err := ffmpeg(ctx, progress, append(args, output)...); err != nil {
+		os.Remove(output)
+		return "", err
+	}
+	return output, nil
+}
+
+// rewrite has ffmpeg write a new version of item.Path next to it, then replaces the file with it.
+// ffmpeg can't write the file it reads.
+func rewrite(ctx context.Context, item *Item, progress func(float64), args ...string) error {
+	ext := filepath.Ext(item.Path)
+	tmp := strings.TrimSuffix(item.Path, ext) + ".temp" + ext
+	if err := ffmpeg(ctx, progress, append(args, tmp)...); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, item.Path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// replaceExt returns path with its extension changed to ext, including the dot.
+func replaceExt(path, ext string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ext
+}
@@ -0,0 +1,328 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+		ok   bool
+	}{
+		{"extract-audio", "extract-audio", true},
+		{"extract-audio:flac", "extract-audio", true},
+		{"extract-audio:ogg", "", false},
+		{"remux:mkv", "remux", true},
+		{"remux", "", false},
+		{"remux:avi", "", false},
+		{"recode:h265:crf=26:720p", "recode", true},
+		{"embed-thumbnail", "embed-thumbnail", true},
+		{"tag", "tag", true},
+		{"exec:echo {title}", "exec", true},
+		{"exec:echo '{title}'", "", false},
+		{"exec", "", false},
+		{"move:~/Music/{author}", "move", true},
+		{"webhook:https://example.com/hook", "webhook", true},
+		{"webhook:ftp://example.com", "", false},
+		{"unknown", "", false},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.spec)
+		if (err == nil) != tt.ok {
+			t.Errorf("Parse(%q) error = %v, want ok %v", tt.spec, err, tt.ok)
+			continue
+		}
+		if tt.ok && p.Name() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.spec, p.Name(), tt.want)
+		}
+	}
+
+	chain, err := ParseChain([]string{"remux:mkv", "tag", "exec:true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(chain.Names(), ","); names != "remux,tag,exec" {
+		t.Errorf("chain = %s", names)
+	}
+	if _, err := ParseChain([]string{"tag", "remux"}); err == nil {
+		t.Error("ParseChain accepted an invalid step")
+	}
+}
+
+// step records its name in calls and fails with err.
+type step struct {
+	name  string
+	calls *[]string
+	err   error
+}
+
+func (s step) Name() string {
+	return s.name
+}
+
+func (s step) Process(ctx context.Context, item *Item) error {
+	*s.calls = append(*s.calls, s.name)
+	item.Path += "." + s.name
+	return s.err
+}
+
+func TestChainRun(t *testing.T) {
+	var calls []string
+	failure := errors.New("failed")
+	chain := Chain{step{"a", &calls, nil}, step{"b", &calls, failure}, step{"c", &calls, nil}}
+
+	item := &Item{Path: "file"}
+	err := chain.Run(context.Background(), item)
+	var stepErr *ErrorStep
+	if !errors.As(err, &stepErr) || stepErr.Step != "b" || stepErr.Path != "file.a.b" || !errors.Is(err, failure) {
+		t.Errorf("Run error = %v", err)
+	}
+	if strings.Join(calls, ",") != "a,b" {
+		t.Errorf("steps run = %q, want a,b", calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = nil
+	if err := chain.Run(ctx, &Item{Path: "file"}); !errors.Is(err, context.Canceled) || len(calls) != 0 {
+		t.Errorf("canceled Run = %v, ran %q", err, calls)
+	}
+}
+
+func testItem(path string) *Item {
+	return &Item{
+		Path:  path,
+		Video: models.VideoInfo{ID: "aaaaaaaaaaa", Title: "AC/DC", Author: "Ann", Url: "https://youtu.be/aaaaaaaaaaa"},
+		Index: 3,
+	}
+}
+
+func TestExpand(t *testing.T) {
+	item := testItem(filepath.Join("music", "song.mp3"))
+
+	got := expand("{author}/{title} {index} {id}", item, folderName)
+	if want := "Ann/AC_DC 3 aaaaaaaaaaa"; got != want {
+		t.Errorf("expand = %q, want %q", got, want)
+	}
+
+	got = expand("echo {name} {unknown}", item, nil)
+	if want := "echo " + shellVariable("name") + " {unknown}"; got != want {
+		t.Errorf("expand = %q, want %q", got, want)
+	}
+}
+
+func TestExecCheck(t *testing.T) {
+	tests := []struct {
+		template string
+		ok       bool
+	}{
+		{"notify-send {title}", true},
+		{`notify-send "Saved $YTDL_TITLE"`, true},
+		{`notify-send "{title}"`, false},
+		{`notify-send "by {author}" {title}`, false},
+		{`echo "{" {title}`, true},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, []struct {
+			template string
+			ok       bool
+		}{
+			{"notify-send '{title}'", false},
+			{`echo "\"" {title}`, true},
+			{`echo '"' {title}`, true},
+		}...)
+	}
+	for _, tt := range tests {
+		p := &Exec{Template: tt.template}
+		if err := p.check(); (err == nil) != tt.ok {
+			t.Errorf("check(%q) = %v, want ok %v", tt.template, err, tt.ok)
+		}
+	}
+}
+
+func TestExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are written for sh")
+	}
+	dir := t.TempDir()
+	item := testItem(filepath.Join(dir, "song.mp3"))
+	for _, title := range []string{`it's "quoted"`, "$(touch pwned); touch pwned", "`touch pwned` & %PATH%"} {
+		item.Video.Title = title
+		out := filepath.Join(dir, "out")
+		p := &Exec{Template: "test -f {path} || printf %s {title} > " + out}
+		if err := p.Process(context.Background(), item); err != nil {
+			t.Fatalf("Process: %v", err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != title {
+			t.Errorf("command got title %q, want %q", data, title)
+		}
+	}
+	if _, err := os.Stat("pwned"); err == nil {
+		os.Remove("pwned")
+		t.Error("a title ran as a command")
+	}
+
+	// the path is appended when the template doesn't use it
+	out := filepath.Join(dir, "path")
+	p := &Exec{Template: "sh -c 'printf %s \"$1\" > " + out + "' sh"}
+	if err := p.Process(context.Background(), item); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != item.Path {
+		t.Errorf("command got path %q, want %q", data, item.Path)
+	}
+
+	if err := (&Exec{Template: "exit 3"}).Process(context.Background(), item); err == nil {
+		t.Error("a failing command succeeded")
+	}
+}
+
+// fakeFFmpeg points FFmpeg to a script copying the input to the output, which records its runs.
+func fakeFFmpeg(t *testing.T) (runs func() int) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "runs")
+	script := `#!/bin/sh
+echo run >> ` + log + `
+prev=""
+for a in "$@"; do
+	[ "$prev" = "-i" ] && in="$a"
+	prev="$a"
+done
+cp "$in" "$prev"
+`
+	path := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	previous := FFmpeg
+	FFmpeg = path
+	t.Cleanup(func() { FFmpeg = previous })
+	return func() int {
+		data, _ := os.ReadFile(log)
+		return strings.Count(string(data), "run")
+	}
+}
+
+func TestConvertConflict(t *testing.T) {
+	runs := fakeFFmpeg(t)
+	dir := t.TempDir()
+	video := filepath.Join(dir, "song.mp4")
+	audio := filepath.Join(dir, "song.mp3")
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+	p := &ExtractAudio{Keep: true}
+
+	// nothing in the way
+	item := &Item{Path: video}
+	write(video, "media")
+	if err := p.Process(context.Background(), item); err != nil || item.Path != audio || read(audio) != "media" || runs() != 1 {
+		t.Fatalf("Process = %v, path %s, %d runs", err, item.Path, runs())
+	}
+
+	// without Output an existing file is refused
+	write(audio, "old")
+	item = &Item{Path: video}
+	var exists *ErrorDestinationExists
+	if err := p.Process(context.Background(), item); !errors.As(err, &exists) || read(audio) != "old" || runs() != 1 {
+		t.Errorf("Process = %v, want ErrorDestinationExists", err)
+	}
+
+	// kept
+	item = &Item{Path: video, Output: func(path string) (string, bool, error) { return path, true, nil }}
+	if err := p.Process(context.Background(), item); err != nil || item.Path != audio || read(audio) != "old" || runs() != 1 {
+		t.Errorf("kept: Process = %v, path %s, %d runs", err, item.Path, runs())
+	}
+
+	// renamed
+	renamed := filepath.Join(dir, "song (1).mp3")
+	item = &Item{Path: video, Output: func(path string) (string, bool, error) { return renamed, false, nil }}
+	if err := p.Process(context.Background(), item); err != nil || item.Path != renamed || read(renamed) != "media" || read(audio) != "old" {
+		t.Errorf("renamed: Process = %v, path %s", err, item.Path)
+	}
+
+	// replaced
+	item = &Item{Path: video, Output: func(path string) (string, bool, error) { return path, false, nil }}
+	if err := p.Process(context.Background(), item); err != nil || item.Path != audio || read(audio) != "media" {
+		t.Errorf("replaced: Process = %v, path %s, content %q", err, item.Path, read(audio))
+	}
+}
+
+func TestMoveConflict(t *testing.T) {
+	src := t.TempDir()
+	dir := t.TempDir()
+	song := filepath.Join(src, "song.mp3")
+	moved := filepath.Join(dir, "song.mp3")
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+	p := &Move{Dir: dir}
+
+	// nothing in the way
+	write(song, "old")
+	item := &Item{Path: song}
+	if err := p.Process(context.Background(), item); err != nil || item.Path != moved || read(moved) != "old" {
+		t.Fatalf("Process = %v, path %s", err, item.Path)
+	}
+
+	// without Output an existing file is refused, the file stays where it is
+	write(song, "new")
+	item = &Item{Path: song}
+	var exists *ErrorDestinationExists
+	if err := p.Process(context.Background(), item); !errors.As(err, &exists) || item.Path != song || read(moved) != "old" {
+		t.Errorf("Process = %v, want ErrorDestinationExists", err)
+	}
+
+	// kept, the file isn't needed anymore
+	item = &Item{Path: song, Output: func(path string) (string, bool, error) { return path, true, nil }}
+	if err := p.Process(context.Background(), item); err != nil || item.Path != moved || read(moved) != "old" {
+		t.Errorf("kept: Process = %v, path %s", err, item.Path)
+	}
+	if _, err := os.Stat(song); !os.IsNotExist(err) {
+		t.Errorf("kept: %s is left behind", song)
+	}
+
+	// renamed
+	write(song, "new")
+	renamed := filepath.Join(dir, "song (2).mp3")
+	item = &Item{Path: song, Output: func(path string) (string, bool, error) { return renamed, false, nil }}
+	if err := p.Process(context.Background(), item); err != nil || item.Path != renamed || read(renamed) != "new" || read(moved) != "old" {
+		t.Errorf("renamed: Process = %v, path %s", err, item.Path)
+	}
+
+	// replaced
+	write(song, "newer")
+	item = &Item{Path: song, Output: func(path string) (string, bool, error) { return path, false, nil }}
+	if err := p.Process(context.Background(), item); err != nil || item.Path != moved || read(moved) != "newer" {
+		t.Errorf("replaced: Process = %v, path %s, content %q", err, item.Path, read(moved))
+	}
+}
@@ -0,0 +1,123 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// videoCodec is how Recode encodes with a codec.
+type videoCodec struct {
+	encoder string
+	// ext is the container written, including the dot.
+	ext string
+	// crf is used when Recode.CRF is 0, maxCRF is the worst quality the encoder accepts.
+	crf, maxCRF int
+	options     []string
+	audio       []string
+}
+
+var (
+	aacAudio  = []string{"-c:a", "aac", "-b:a", "192k"}
+	opusAudio = []string{"-c:a", "libopus", "-b:a", "128k"}
+)
+
+// videoCodecs are the codecs Recode encodes to.
+var videoCodecs = map[string]videoCodec{
+	"h264": {"libx264", ".mp4", 23, 51, []string{"-preset", "medium", "-pix_fmt", "yuv420p"}, aacAudio},
+	"h265": {"libx265", ".mp4", 28, 51, []string{"-preset", "medium", "-tag:v", "hvc1"}, aacAudio},
+	"vp9":  {"libvpx-vp9", ".webm", 31, 63, []string{"-b:v", "0", "-row-mt", "1"}, opusAudio},
+	"av1":  {"libsvtav1", ".webm", 35, 63, []string{"-preset", "8"}, opusAudio},
+}
+
+// resolutions are the presets Recode scales videos down to, by their height.
+var resolutions = map[string]int{
+	"360p": 360, "480p": 480, "720p": 720, "1080p": 1080, "1440p": 1440, "2160p": 2160, "4k": 2160,
+}
+
+// Recode re-encodes the video with another codec, the original file is replaced.
+// mp4 files are written for h264 and h265, webm files for vp9 and av1.
+// Its progress is reported to item.Stage when the duration of the video is known.
+type Recode struct {
+	// Codec is h264, h265, vp9 or av1.
+	Codec string
+	// CRF is the constant rate factor, lower means better and bigger. The codec default when 0.
+	CRF int
+	// Height scales videos taller than that down, keeping their aspect ratio. Nothing is scaled when 0.
+	Height int
+}
+
+// parseRecode parses "<codec>[:crf=<n>][:<resolution>]".
+func parseRecode(arg string) (*Recode, error) {
+	fields := strings.Split(arg, ":")
+	p := &Recode{Codec: fields[0]}
+	for _, field := range fields[1:] {
+		if value, ok := strings.CutPrefix(field, "crf="); ok {
+			crf, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("recode crf must be a number, got '%s'", value)
+			}
+			p.CRF = crf
+		} else if height, ok := resolutions[strings.ToLower(field)]; ok {
+			p.Height = height
+		} else {
+			return nil, fmt.Errorf("unknown recode option '%s', expected crf=<n> or a resolution: 360p, 480p, 720p, 1080p, 1440p, 2160p or 4k", field)
+		}
+	}
+
+	if _, err := p.codec(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Recode) Name() string {
+	return "recode"
+}
+
+func (p *Recode) codec() (videoCodec, error) {
+	codec, ok := videoCodecs[strings.ToLower(p.Codec)]
+	if !ok {
+		return codec, fmt.Errorf("unknown codec '%s', expected h264, h265, vp9 or av1", p.Codec)
+	}
+	if p.CRF < 0 || p.CRF > codec.maxCRF {
+		return codec, fmt.Errorf("%s crf must be between 0 and %d, got %d", p.Codec, codec.maxCRF, p.CRF)
+	}
+	return codec, nil
+}
+
+func (p *Recode) Process(ctx context.Context, item *Item) error {
+	codec, err := p.codec()
+	if err != nil {
+		return err
+	}
+	crf := p.CRF
+	if crf == 0 {
+		crf = codec.crf
+	}
+
+	// V skips cover art, the audio is optional
+	args := []string{"-i", item.Path, "-map", "0:V:0", "-map", "0:a?", "-c:v", codec.encoder, "-crf", strconv.Itoa(crf)}
+	args = append(args, codec.options...)
+	if p.Height > 0 {
+		args = append(args, "-vf", fmt.Sprintf("scale=-2:'min(%d,ih)'", p.Height))
+	}
+	args = append(args, codec.audio...)
+
+	progress := item.ffmpegProgress("recoding")
+	output := replaceExt(item.Path, codec.ext)
+	if output == item.Path {
+		return rewrite(ctx, item, progress, args...)
+	}
+	output, err = convert(ctx, item, output, progress, args...)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(item.Path); err != nil {
+		return err
+	}
+	item.Path = output
+	return nil
+}
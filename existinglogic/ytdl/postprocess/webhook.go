@@ -0,0 +1,41 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Webhook posts the item as JSON to URL: its path, index, video metadata and format.
+// Any answer but 2xx fails the step.
+type Webhook struct {
+	URL string
+}
+
+func (p *Webhook) Name() string {
+	return "webhook"
+}
+
+func (p *Webhook) Process(ctx context.Context, item *Item) error {
+	body, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := item.client().Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", p.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/gosuri/uiprogress"
+	"github.com/gosuri/uiprogress/util/strutil"
+)
+
+// barScale is the resolution of the bars, uiprogress counts in ints.
+const barScale = 1000
+
+const (
+	barWidth     = 40
+	barNameWidth = 44
+)
+
+// Bars draws a terminal progress bar per task, tasks started later get a new bar below.
+// Write other output through Bypass so it doesn't break the bars.
+type Bars struct {
+	mu       sync.Mutex
+	progress *uiprogress.Progress
+	bars     map[string]*bar
+}
+
+// bar is the state shown by one uiprogress bar, read by its decorators while rendering.
+type bar struct {
+	mu sync.Mutex
+	ui *uiprogress.Bar
+	transfer
+	stage string
+}
+
+// NewBars starts drawing to w until Stop is called.
+func NewBars(w io.Writer) *Bars {
+	p := uiprogress.New()
+	p.SetOut(w)
+	p.Width = barWidth
+	p.Start()
+	return &Bars{progress: p, bars: make(map[string]*bar)}
+}
+
+// Bypass returns a writer printing above the bars.
+func (b *Bars) Bypass() io.Writer {
+	return b.progress.Bypass()
+}
+
+// Stop draws the bars a last time and stops refreshing them.
+func (b *Bars) Stop() {
+	b.progress.Stop()
+}
+
+func (b *Bars) bar(id string) *bar {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if task, ok := b.bars[id]; ok {
+		return task
+	}
+	task := &bar{transfer: transfer{name: id}}
+	task.ui = b.progress.AddBar(barScale)
+	task.ui.PrependFunc(func(*uiprogress.Bar) string {
+		task.mu.Lock()
+		defer task.mu.Unlock()
+		return fmt.Sprintf("[%s]", strutil.PadRight(task.stage, 11, ' '))
+	})
+	task.ui.AppendFunc(func(*uiprogress.Bar) string {
+		task.mu.Lock()
+		defer task.mu.Unlock()
+		return fmt.Sprintf("%s - %s", strutil.Resize(task.name, barNameWidth), task.size())
+	})
+	b.bars[id] = task
+	return task
+}
+
+// set updates the state of a bar, done fills it whatever the size.
+func (t *bar) set(update func(t *bar), done bool) {
+	t.mu.Lock()
+	update(t)
+	current := 0
+	if t.total > 0 {
+		current = int(t.bytes * barScale / t.total)
+	}
+	t.mu.Unlock()
+
+	if done || current > barScale {
+		current = barScale
+	}
+	t.ui.Set(current)
+}
+
+func (b *Bars) Start(id string, name string, total int64) {
+	b.bar(id).set(func(t *bar) {
+		t.start(name, total)
+		t.stage = "downloading"
+	}, false)
+}
+
+func (b *Bars) Bytes(id string, n int64) {
+	b.bar(id).set(func(t *bar) { t.bytes = n }, false)
+}
+
+func (b *Bars) Stage(id string, stage string) {
+	b.bar(id).set(func(t *bar) { t.stage = stage }, false)
+}
+
+func (b *Bars) Done(id string) {
+	b.bar(id).set(func(t *bar) { t.stage = "done" }, true)
+}
+
+func (b *Bars) Error(id string, err error) {
+	b.bar(id).set(func(t *bar) { t.stage = "error" }, false)
+}
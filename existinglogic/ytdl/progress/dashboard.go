@@ -0,0 +1,216 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gosuri/uilive"
+	"github.com/gosuri/uiprogress/util/strutil"
+)
+
+const (
+	// dashboardInterval is how often the dashboard is redrawn.
+	dashboardInterval = 200 * time.Millisecond
+	// speedInterval is how often speeds are sampled, longer than the updates of downloads.
+	speedInterval      = time.Second
+	dashboardNameWidth = 40
+)
+
+// Dashboard redraws a line per active task with its percent, speed, ETA and stage,
+// under a totals line with the done, failed and remaining tasks and the overall speed.
+// Finished tasks leave the dashboard. Write other output through Bypass.
+type Dashboard struct {
+	mu     sync.Mutex
+	live   *uilive.Writer
+	tasks  map[string]*dashboardTask
+	done   int
+	failed int
+	// bytes of finished tasks, the overall speed counts active ones too
+	finishedBytes int64
+	lastBytes     int64
+	lastSample    time.Time
+	speed         float64
+	stop          chan struct{}
+	stopped       chan struct{}
+}
+
+type dashboardTask struct {
+	transfer
+	stage string
+	// order keeps lines in the order tasks were first seen
+	order int
+}
+
+// NewDashboard starts drawing to w until Stop is called.
+func NewDashboard(w io.Writer) *Dashboard {
+	live := uilive.New()
+	live.Out = w
+
+	d := &Dashboard{
+		live:       live,
+		lastSample: time.Now(),
+		tasks:      make(map[string]*dashboardTask),
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Bypass returns a writer printing above the dashboard.
+func (d *Dashboard) Bypass() io.Writer {
+	return d.live.Bypass()
+}
+
+// Stop draws the dashboard a last time and stops refreshing it.
+func (d *Dashboard) Stop() {
+	close(d.stop)
+	<-d.stopped
+}
+
+func (d *Dashboard) run() {
+	defer close(d.stopped)
+
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			d.draw()
+			return
+		case now := <-ticker.C:
+			if now.Sub(d.lastSample) >= speedInterval {
+				d.sample(now)
+			}
+			d.draw()
+		}
+	}
+}
+
+// sample updates the moving averages of speeds with the bytes since the last sample.
+func (d *Dashboard) sample(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seconds := now.Sub(d.lastSample).Seconds()
+	if d.lastSample.IsZero() {
+		seconds = speedInterval.Seconds()
+	}
+	d.lastSample = now
+	total := d.finishedBytes
+	for _, task := range d.tasks {
+		task.sample(seconds)
+		total += task.bytes
+	}
+	d.speed = smooth(d.speed, float64(total-d.lastBytes)/seconds)
+	d.lastBytes = total
+}
+
+func (d *Dashboard) draw() {
+	d.mu.Lock()
+	tasks := make([]*dashboardTask, 0, len(d.tasks))
+	remaining := 0
+	for _, task := range d.tasks {
+		remaining++
+		if task.stage != "queued" {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].order < tasks[j].order })
+
+	for _, task := range tasks {
+		fmt.Fprintln(d.live, task.line())
+	}
+	fmt.Fprintf(d.live, "%d done, %d failed, %d remaining - %s/s\n",
+		d.done, d.failed, remaining, formatBytes(int64(d.speed)))
+	d.mu.Unlock()
+
+	d.live.Flush()
+}
+
+// line is the dashboard line of a task:
+// name  42%  12.3 MB of 29.0 MB  1.2 MB/s  ETA 0:14  downloading
+func (t *dashboardTask) line() string {
+	name := strutil.PadRight(strutil.Resize(t.name, dashboardNameWidth), dashboardNameWidth, ' ')
+	if t.bytes == 0 && t.total <= 0 {
+		return fmt.Sprintf("%s  %s", name, t.stage)
+	}
+
+	percent, eta := "   ?", "--:--"
+	if p, ok := t.percent(); ok {
+		percent = fmt.Sprintf("%3d%%", p)
+	}
+	if left, ok := t.eta(); ok {
+		eta = formatETA(left)
+	}
+	return fmt.Sprintf("%s  %s  %-20s  %9s/s  ETA %s  %s",
+		name, percent, t.size(), formatBytes(int64(t.speed)), eta, t.stage)
+}
+
+// formatETA prints d like 1:02:03 or 2:03.
+func formatETA(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+func (d *Dashboard) task(id string) *dashboardTask {
+	task, ok := d.tasks[id]
+	if !ok {
+		task = &dashboardTask{transfer: transfer{name: id}, order: len(d.tasks) + d.done + d.failed}
+		d.tasks[id] = task
+	}
+	return task
+}
+
+func (d *Dashboard) Start(id string, name string, total int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	task := d.task(id)
+	task.start(name, total)
+	task.stage = "downloading"
+}
+
+func (d *Dashboard) Bytes(id string, n int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.task(id).bytes = n
+}
+
+func (d *Dashboard) Stage(id string, stage string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.task(id).stage = stage
+}
+
+func (d *Dashboard) Done(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.finish(id)
+	d.done++
+}
+
+func (d *Dashboard) Error(id string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.finish(id)
+	d.failed++
+}
+
+// finish removes a task from the dashboard, its bytes still count in the overall speed.
+func (d *Dashboard) finish(id string) {
+	if task, ok := d.tasks[id]; ok {
+		d.finishedBytes += task.bytes
+		delete(d.tasks, id)
+	}
+}
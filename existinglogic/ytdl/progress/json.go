@@ -0,0 +1,60 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Update is a line written by JSON.
+type Update struct {
+	// Type is start, bytes, stage, done or error.
+	Type  string    `json:"type"`
+	Time  time.Time `json:"time"`
+	ID    string    `json:"id"`
+	Name  string    `json:"name,omitempty"`
+	Bytes int64     `json:"bytes,omitempty"`
+	Total int64     `json:"total,omitempty"`
+	Stage string    `json:"stage,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// JSON writes every report as a line of JSON, see Update.
+type JSON struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSON reports to w.
+func NewJSON(w io.Writer) *JSON {
+	return &JSON{enc: json.NewEncoder(w)}
+}
+
+func (j *JSON) write(update Update) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	update.Time = time.Now()
+	j.enc.Encode(update)
+}
+
+func (j *JSON) Start(id string, name string, total int64) {
+	j.write(Update{Type: "start", ID: id, Name: name, Total: total})
+}
+
+func (j *JSON) Bytes(id string, n int64) {
+	j.write(Update{Type: "bytes", ID: id, Bytes: n})
+}
+
+func (j *JSON) Stage(id string, stage string) {
+	j.write(Update{Type: "stage", ID: id, Stage: stage})
+}
+
+func (j *JSON) Done(id string) {
+	j.write(Update{Type: "done", ID: id})
+}
+
+func (j *JSON) Error(id string, err error) {
+	j.write(Update{Type: "error", ID: id, Error: err.Error()})
+}
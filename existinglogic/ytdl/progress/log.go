@@ -0,0 +1,103 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// logStep is how often, in percent, Log prints the progress of a transfer of known size.
+const logStep = 25
+
+// logUnknownStep is how often, in bytes, Log prints the progress of a transfer of unknown size.
+const logUnknownStep = 10 << 20
+
+// Log prints a line per stage, every few percent of a transfer and the speed once it's done,
+// for output that isn't a terminal like files or CI logs.
+type Log struct {
+	mu    sync.Mutex
+	w     io.Writer
+	tasks map[string]*logTask
+}
+
+type logTask struct {
+	transfer
+	printed int64
+}
+
+// NewLog reports to w.
+func NewLog(w io.Writer) *Log {
+	return &Log{w: w, tasks: make(map[string]*logTask)}
+}
+
+func (l *Log) task(id string) *logTask {
+	task, ok := l.tasks[id]
+	if !ok {
+		task = &logTask{transfer: transfer{name: id}}
+		l.tasks[id] = task
+	}
+	return task
+}
+
+func (l *Log) Start(id string, name string, total int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	task := l.task(id)
+	task.start(name, total)
+	task.printed = 0
+	if total > 0 {
+		fmt.Fprintf(l.w, "%s: downloading %s\n", name, formatBytes(total))
+	} else {
+		fmt.Fprintf(l.w, "%s: downloading\n", name)
+	}
+}
+
+func (l *Log) Bytes(id string, n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	task := l.task(id)
+	task.bytes = n
+	if percent, ok := task.percent(); ok {
+		if percent/logStep > task.printed/logStep && percent < 100 {
+			task.printed = percent
+			fmt.Fprintf(l.w, "%s: %d%% of %s\n", task.name, percent, formatBytes(task.total))
+		}
+		return
+	}
+	if n/logUnknownStep > task.printed/logUnknownStep {
+		task.printed = n
+		fmt.Fprintf(l.w, "%s: %s\n", task.name, formatBytes(n))
+	}
+}
+
+func (l *Log) Stage(id string, stage string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(l.w, "%s: %s\n", l.task(id).name, stage)
+}
+
+func (l *Log) Done(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	task := l.task(id)
+	if task.bytes > 0 && !task.started.IsZero() {
+		fmt.Fprintf(l.w, "%s: done, %s in %s (%s/s)\n", task.name, formatBytes(task.bytes),
+			time.Since(task.started).Round(time.Millisecond), formatBytes(int64(task.averageSpeed())))
+	} else {
+		fmt.Fprintf(l.w, "%s: done\n", task.name)
+	}
+	delete(l.tasks, id)
+}
+
+func (l *Log) Error(id string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(l.w, "%s: error: %v\n", l.task(id).name, err)
+	delete(l.tasks, id)
+}
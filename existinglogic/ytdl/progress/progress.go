@@ -0,0 +1,43 @@
+// Package progress reports the state of downloads and the steps around them,
+// to terminal bars, log lines, JSON or nowhere.
+package progress
+
+import "fmt"
+
+// Reporter receives the progress of tasks identified by id, usually the link being downloaded.
+// Implementations are safe for concurrent use and accept calls for ids they haven't seen started.
+type Reporter interface {
+	// Start announces a transfer of total bytes, 0 or less when the size is unknown.
+	Start(id string, name string, total int64)
+	// Bytes reports how many bytes of the transfer are done so far.
+	Bytes(id string, n int64)
+	// Stage reports a step that isn't a transfer, like fetching metadata or converting.
+	Stage(id string, stage string)
+	// Done marks the task as finished.
+	Done(id string)
+	// Error marks the task as failed.
+	Error(id string, err error)
+}
+
+// Nop reports nothing, for library callers and tests.
+type Nop struct{}
+
+func (Nop) Start(string, string, int64) {}
+func (Nop) Bytes(string, int64)         {}
+func (Nop) Stage(string, string)        {}
+func (Nop) Done(string)                 {}
+func (Nop) Error(string, error)         {}
+
+// formatBytes prints n like 12.3 MB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,37 @@
+package progress
+
+import (
+	"io"
+	"time"
+)
+
+// ReportInterval is how often a Reader reports the bytes of a download.
+const ReportInterval = 250 * time.Millisecond
+
+// Reader wraps the body of a transfer and reports to Reporter how many bytes went through it,
+// at most every Interval (every read when 0) and at the end of the body.
+type Reader struct {
+	io.Reader
+	Reporter Reporter
+	// ID is the task the bytes are reported for.
+	ID       string
+	Interval time.Duration
+
+	n        int64
+	reported time.Time
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	if time.Since(r.reported) >= r.Interval || err == io.EOF {
+		r.reported = time.Now()
+		r.Reporter.Bytes(r.ID, r.n)
+	}
+	return n, err
+}
+
+// N returns how many bytes were read so far.
+func (r *Reader) N() int64 {
+	return r.n
+}
@@ -0,0 +1,61 @@
+package progress
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// recorder keeps the bytes reported for every task.
+type recorder struct {
+	Nop
+	mu    sync.Mutex
+	bytes map[string][]int64
+}
+
+func (r *recorder) Bytes(id string, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.bytes == nil {
+		r.bytes = make(map[string][]int64)
+	}
+	r.bytes[id] = append(r.bytes[id], n)
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     []int64
+	}{
+		{"every read", 0, []int64{1, 2, 3, 4, 5, 5}},
+		// the first read and the end of the body are always reported
+		{"throttled", time.Hour, []int64{1, 5}},
+	}
+	for _, tt := range tests {
+		reporter := &recorder{}
+		reader := &Reader{Reader: iotest.OneByteReader(strings.NewReader("12345")), Reporter: reporter, ID: "task", Interval: tt.interval}
+
+		data, err := io.ReadAll(reader)
+		if err != nil || string(data) != "12345" {
+			t.Fatalf("%s: read %q, %v", tt.name, data, err)
+		}
+		if reader.N() != 5 {
+			t.Errorf("%s: N = %d, want 5", tt.name, reader.N())
+		}
+		got := reporter.bytes["task"]
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: reported %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: reported %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package progress
+
+import "time"
+
+// speedSmoothing weighs the last sample in the moving average of speeds.
+const speedSmoothing = 0.3
+
+// transfer is what the reporters know about a task: the bytes reported by its Reader,
+// out of total when known, and how fast they come.
+type transfer struct {
+	name    string
+	bytes   int64
+	total   int64
+	started time.Time
+	// sampled is bytes at the last speed sample
+	sampled int64
+	speed   float64
+}
+
+// start resets the transfer for a download of total bytes.
+func (t *transfer) start(name string, total int64) {
+	*t = transfer{name: name, total: total, started: time.Now()}
+}
+
+// sample updates the moving average of the speed with the bytes of the last seconds.
+func (t *transfer) sample(seconds float64) {
+	t.speed = smooth(t.speed, float64(t.bytes-t.sampled)/seconds)
+	t.sampled = t.bytes
+}
+
+// averageSpeed is the speed since the start, in bytes per second.
+func (t *transfer) averageSpeed() float64 {
+	elapsed := time.Since(t.started).Seconds()
+	if t.started.IsZero() || elapsed <= 0 {
+		return 0
+	}
+	return float64(t.bytes) / elapsed
+}
+
+// percent is how much of the transfer is done, false when the size is unknown.
+func (t *transfer) percent() (int64, bool) {
+	if t.total <= 0 {
+		return 0, false
+	}
+	return t.bytes * 100 / t.total, true
+}
+
+// eta is the time left at the sampled speed, false when it can't be told.
+func (t *transfer) eta() (time.Duration, bool) {
+	if t.total <= 0 || t.speed <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(t.total-t.bytes) / t.speed * float64(time.Second)), true
+}
+
+// size prints the bytes like 12.3 MB of 29.0 MB.
+func (t *transfer) size() string {
+	size := formatBytes(t.bytes)
+	if t.total > 0 {
+		size += " of " + formatBytes(t.total)
+	}
+	return size
+}
+
+func smooth(average float64, sample float64) float64 {
+	if average == 0 {
+		return sample
+	}
+	return speedSmoothing*sample + (1-speedSmoothing)*average
+}
@@ -0,0 +1,93 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransfer(t *testing.T) {
+	var task transfer
+	task.start("video.mp4", 4<<20)
+	task.bytes = 1 << 20
+	task.sample(1)
+	task.bytes = 2 << 20
+	task.sample(1)
+
+	if percent, ok := task.percent(); !ok || percent != 50 {
+		t.Errorf("percent = %d, %v, want 50", percent, ok)
+	}
+	// 1 MB/s then 1 MB/s
+	if task.speed != 1<<20 {
+		t.Errorf("speed = %.0f, want %d", task.speed, 1<<20)
+	}
+	if eta, ok := task.eta(); !ok || eta != 2*time.Second {
+		t.Errorf("eta = %v, %v, want 2s", eta, ok)
+	}
+	if size := task.size(); size != "2.0 MB of 4.0 MB" {
+		t.Errorf("size = %q", size)
+	}
+
+	// a new start forgets the previous download
+	task.start("audio.m4a", 0)
+	if _, ok := task.percent(); ok || task.bytes != 0 || task.speed != 0 {
+		t.Errorf("restarted transfer = %+v", task)
+	}
+	if _, ok := task.eta(); ok {
+		t.Error("eta of an unknown size is known")
+	}
+	task.bytes = 1500
+	if size := task.size(); size != "1.5 KB" {
+		t.Errorf("size = %q", size)
+	}
+}
+
+func TestDashboardLine(t *testing.T) {
+	task := &dashboardTask{transfer: transfer{name: "video.mp4"}, stage: "queued"}
+	if line := task.line(); !strings.HasPrefix(line, "video.mp4 ") || !strings.HasSuffix(line, "  queued") {
+		t.Errorf("line = %q", line)
+	}
+
+	task.start("video.mp4", 4<<20)
+	task.stage = "downloading"
+	task.bytes = 1 << 20
+	task.sample(1)
+	fields := strings.Fields(task.line())
+	want := []string{"video.mp4", "25%", "1.0", "MB", "of", "4.0", "MB", "1.0", "MB/s", "ETA", "0:03", "downloading"}
+	if strings.Join(fields, " ") != strings.Join(want, " ") {
+		t.Errorf("line = %q, want %q", fields, want)
+	}
+}
+
+func TestLog(t *testing.T) {
+	var out bytes.Buffer
+	log := NewLog(&out)
+
+	log.Start("a", "video.mp4", 100)
+	for _, n := range []int64{10, 30, 40, 60, 100} {
+		log.Bytes("a", n)
+	}
+	log.Done("a")
+	log.Stage("b", "converting")
+	log.Error("b", errors.New("ffmpeg failed"))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"video.mp4: downloading 100 B",
+		"video.mp4: 30% of 100 B",
+		"video.mp4: 60% of 100 B",
+		"video.mp4: done, 100 B in",
+		"b: converting",
+		"b: error: ffmpeg failed",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("log =\n%s", out.String())
+	}
+	for i := range want {
+		if !strings.HasPrefix(lines[i], want[i]) {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status of a job in the queue.
+type Status string
+
+const (
+	StatusQueued   Status = "queued"
+	StatusRunning  Status = "running"
+	StatusDone     Status = "done"
+	StatusFailed   Status = "failed"
+	StatusCanceled Status = "canceled"
+)
+
+// Job is a single link waiting to be (or already) handled by the downloader.
+type Job struct {
+	ID            string    `json:"id"`
+	Link          string    `json:"link"`
+	OutputDir     string    `json:"outputDir"`
+	VideoOnly     bool      `json:"videoOnly"`
+	IncludeAuthor bool      `json:"includeAuthor"`
+	Status        Status    `json:"status"`
+	Errors        []string  `json:"errors,omitempty"`
+	Attempts      int       `json:"attempts"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// ErrorJobNotFound no job with the given id exists in the store.
+type ErrorJobNotFound struct {
+	id string
+}
+
+func (e *ErrorJobNotFound) Error() string {
+	return fmt.Sprintf("job %q not found", e.id)
+}
+
+// ErrorJobState the job can't go through the requested transition.
+type ErrorJobState struct {
+	id     string
+	status Status
+	action string
+}
+
+func (e *ErrorJobState) Error() string {
+	return fmt.Sprintf("can't %v job %q while it is %v", e.action, e.id, e.status)
+}
+
+// Store keeps jobs as one JSON file per job inside dir,
+// so the queue survives restarts of the daemon.
+type Store struct {
+	dir  string
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// Open loads every job found in dir (creating dir if needed).
+// Jobs that were running when the previous process died are queued again.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &Store{dir: dir, jobs: make(map[string]*Job)}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("corrupt job file %q: %w", entry.Name(), err)
+		}
+
+		// resume jobs that were interrupted mid download
+		if job.Status == StatusRunning {
+			job.Status = StatusQueued
+			if err := store.save(&job); err != nil {
+				return nil, err
+			}
+		}
+		store.jobs[job.ID] = &job
+	}
+
+	return store, nil
+}
+
+// Enqueue adds a new job for link and returns a copy of it.
+func (s *Store) Enqueue(job Job) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	now := time.Now()
+	job.ID = id
+	job.Status = StatusQueued
+	job.Errors = nil
+	job.Attempts = 0
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if err := s.save(&job); err != nil {
+		return Job{}, err
+	}
+	s.jobs[job.ID] = &job
+
+	return job, nil
+}
+
+// List returns copies of all jobs, oldest first.
+func (s *Store) List() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs
+}
+
+// Get returns a copy of the job with the given id.
+func (s *Store) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, &ErrorJobNotFound{id}
+	}
+	return *job, nil
+}
+
+// Next claims the oldest queued job and marks it as running.
+// The second return value is false when nothing is queued.
+func (s *Store) Next() (Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next *Job
+	for _, job := range s.jobs {
+		if job.Status != StatusQueued {
+			continue
+		}
+		if next == nil || job.CreatedAt.Before(next.CreatedAt) {
+			next = job
+		}
+	}
+	if next == nil {
+		return Job{}, false, nil
+	}
+
+	job := *next
+	job.Status = StatusRunning
+	job.Attempts++
+	job.UpdatedAt = time.Now()
+	if err := s.update(next, job); err != nil {
+		return Job{}, false, err
+	}
+
+	return job, true, nil
+}
+
+// Finish records the outcome of a running job.
+// A job canceled while it was running stays canceled.
+func (s *Store) Finish(id string, errs []error) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, &ErrorJobNotFound{id}
+	}
+	if job.Status != StatusRunning {
+		return *job, nil
+	}
+
+	finished := *job
+	finished.Errors = nil
+	for _, err := range errs {
+		finished.Errors = append(finished.Errors, err.Error())
+	}
+	if len(errs) > 0 {
+		finished.Status = StatusFailed
+	} else {
+		finished.Status = StatusDone
+	}
+	finished.UpdatedAt = time.Now()
+
+	if err := s.update(job, finished); err != nil {
+		return Job{}, err
+	}
+	return finished, nil
+}
+
+// Cancel stops a queued job from being picked up.
+// Running jobs are marked as canceled and their outcome is discarded.
+func (s *Store) Cancel(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, &ErrorJobNotFound{id}
+	}
+	if job.Status != StatusQueued && job.Status != StatusRunning {
+		return Job{}, &ErrorJobState{id, job.Status, "cancel"}
+	}
+
+	canceled := *job
+	canceled.Status = StatusCanceled
+	canceled.UpdatedAt = time.Now()
+
+	if err := s.update(job, canceled); err != nil {
+		return Job{}, err
+	}
+	return canceled, nil
+}
+
+// Retry queues a failed or canceled job again.
+func (s *Store) Retry(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, &ErrorJobNotFound{id}
+	}
+	if job.Status != StatusFailed && job.Status != StatusCanceled {
+		return Job{}, &ErrorJobState{id, job.Status, "retry"}
+	}
+
+	queued := *job
+	queued.Status = StatusQueued
+	queued.Errors = nil
+	queued.UpdatedAt = time.Now()
+
+	if err := s.update(job, queued); err != nil {
+		return Job{}, err
+	}
+	return queued, nil
+}
+
+// update saves the new state of job and only then applies it to job,
+// so a failed write leaves the job as it is on disk.
+func (s *Store) update(job *Job, state Job) error {
+	if err := s.save(&state); err != nil {
+		return err
+	}
+	*job = state
+	return nil
+}
+
+// save writes the job next to the others, going through a temp file
+// so a crash never leaves a half written job behind.
+func (s *Store) save(job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, job.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, job.ID+".json"))
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := store.Enqueue(Job{Link: "https://youtu.be/aaaaaaaaaaa", OutputDir: "/music", Status: StatusDone, Attempts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == "" || first.Status != StatusQueued || first.Attempts != 0 || first.OutputDir != "/music" {
+		t.Errorf("enqueued %+v", first)
+	}
+	second, err := store.Enqueue(Job{Link: "https://youtu.be/bbbbbbbbbbb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the oldest job goes first
+	job, ok, err := store.Next()
+	if err != nil || !ok || job.ID != first.ID || job.Status != StatusRunning || job.Attempts != 1 {
+		t.Fatalf("Next = %+v, %v, %v", job, ok, err)
+	}
+	if job, err := store.Finish(first.ID, []error{errors.New("http 403")}); err != nil || job.Status != StatusFailed || len(job.Errors) != 1 {
+		t.Errorf("Finish = %+v, %v", job, err)
+	}
+	if job, err := store.Retry(first.ID); err != nil || job.Status != StatusQueued || job.Errors != nil {
+		t.Errorf("Retry = %+v, %v", job, err)
+	}
+	if _, err := store.Cancel(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	var state *ErrorJobState
+	if _, err := store.Cancel(second.ID); !errors.As(err, &state) {
+		t.Errorf("Cancel of a canceled job = %v", err)
+	}
+	var notFound *ErrorJobNotFound
+	if _, err := store.Get("missing"); !errors.As(err, &notFound) {
+		t.Errorf("Get = %v", err)
+	}
+
+	// a job running when the process died is queued again on the next start
+	if job, ok, err := store.Next(); err != nil || !ok || job.ID != first.ID {
+		t.Fatalf("Next = %+v, %v, %v", job, ok, err)
+	}
+	restarted, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := restarted.List()
+	if len(jobs) != 2 || jobs[0].ID != first.ID || jobs[1].ID != second.ID {
+		t.Fatalf("jobs after restart = %+v", jobs)
+	}
+	if jobs[0].Status != StatusQueued || jobs[0].Attempts != 2 || jobs[0].OutputDir != "/music" {
+		t.Errorf("interrupted job = %+v", jobs[0])
+	}
+	if jobs[1].Status != StatusCanceled {
+		t.Errorf("canceled job = %+v", jobs[1])
+	}
+}
+
+func TestStoreSaveFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "jobs")
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := store.Enqueue(Job{Link: "https://youtu.be/aaaaaaaaaaa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing can be written anymore
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Enqueue(Job{Link: "https://youtu.be/bbbbbbbbbbb"}); err == nil {
+		t.Error("Enqueue succeeded without saving")
+	}
+	if _, _, err := store.Next(); err == nil {
+		t.Error("Next succeeded without saving")
+	}
+	if _, err := store.Cancel(queued.ID); err == nil {
+		t.Error("Cancel succeeded without saving")
+	}
+
+	jobs := store.List()
+	if len(jobs) != 1 || jobs[0].Status != StatusQueued || jobs[0].Attempts != 0 {
+		t.Errorf("jobs after failed saves = %+v", jobs)
+	}
+}
@@ -0,0 +1,106 @@
+package rootpath
+
+import (
+	"path/filepath"
+	"runtime"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// PartSuffix is appended to files while they are being downloaded,
+// names are budgeted so the temporary name fits too.
+const PartSuffix = ".part"
+
+// Limits are the name and path lengths a filesystem accepts,
+// measured by Length in the filesystem's own unit.
+type Limits struct {
+	MaxName int
+	MaxPath int
+	Length  func(string) int
+}
+
+var (
+	// PATH_MAX counts the terminating NUL
+	posixLimits = Limits{MaxName: 255, MaxPath: 4096 - 1, Length: func(s string) int { return len(s) }}
+	// MAX_PATH counts the terminating NUL too, names and paths are stored as UTF-16
+	windowsLimits = Limits{MaxName: WindowsMaxName, MaxPath: WindowsMaxPath - 1, Length: utf16Length}
+)
+
+// Limits returns the limits of the filesystems the profile names files for.
+func (p Profile) Limits() Limits {
+	switch p {
+	case ProfilePosix:
+		return posixLimits
+	case ProfileWindows, ProfileFAT32, ProfileASCII:
+		return windowsLimits
+	default:
+		if runtime.GOOS == "windows" {
+			return windowsLimits
+		}
+		return posixLimits
+	}
+}
+
+// NameBudget returns how long a name created in dir may be so that
+// the name plus suffix fits both the name and the full path limits.
+func NameBudget(dir string, suffix string, profile Profile) int {
+	limits := profile.Limits()
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	budget := limits.MaxName - limits.Length(suffix)
+	// +1 for the separator between dir and the name
+	if available := limits.MaxPath - limits.Length(dir) - 1 - limits.Length(suffix); available < budget {
+		budget = available
+	}
+	return budget
+}
+
+// FolderBudget returns how long the name of a folder created in dir may be,
+// half of what's left of the path stays for the files inside.
+func FolderBudget(dir string, profile Profile) int {
+	limits := profile.Limits()
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	budget := limits.MaxName
+	if available := (limits.MaxPath - limits.Length(dir) - 1) / 2; available < budget {
+		budget = available
+	}
+	return budget
+}
+
+// Truncate cuts s to at most max units of the profile without splitting a character.
+func Truncate(s string, max int, profile Profile) string {
+	length := profile.Limits().Length
+	if length(s) <= max {
+		return s
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 && length(string(runes)) > max {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes)
+}
+
+// truncateUTF8 cuts s to at most maxBytes without splitting a multi-byte character.
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	if maxBytes <= 0 {
+		return ""
+	}
+
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
+
+func utf16Length(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
@@ -0,0 +1,125 @@
+package rootpath
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// deepDir creates a directory in a temp dir whose absolute path is about length bytes long.
+func deepDir(t *testing.T, length int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for len(dir)+len("/"+strings.Repeat("d", 100)) <= length {
+		dir = filepath.Join(dir, strings.Repeat("d", 100))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Skipf("can't create a %d bytes long directory: %v", len(dir), err)
+	}
+	return dir
+}
+
+func TestNameBudget(t *testing.T) {
+	short := t.TempDir()
+	deep := deepDir(t, 4000)
+	// 200 bytes for 100 runes, windows counts 100 units
+	accents := filepath.Join(short, strings.Repeat("é", 100))
+
+	tests := []struct {
+		name    string
+		dir     string
+		suffix  string
+		profile Profile
+		want    int
+	}{
+		{"posix name limit", short, PartSuffix, ProfilePosix, 255 - 5},
+		{"posix without suffix", short, "", ProfilePosix, 255},
+		{"posix path limit", deep, PartSuffix, ProfilePosix, 4095 - len(deep) - 1 - 5},
+		{"windows path limit", short, PartSuffix, ProfileWindows, 259 - len(short) - 1 - 5},
+		{"fat32 path limit", short, PartSuffix, ProfileFAT32, 259 - len(short) - 1 - 5},
+		{"windows counts utf-16", accents, PartSuffix, ProfileWindows, 259 - (len(short) + 1 + 100) - 1 - 5},
+		{"posix counts bytes", accents, PartSuffix, ProfilePosix, 255 - 5},
+		{"windows path exhausted", deep, PartSuffix, ProfileWindows, 259 - len(deep) - 1 - 5},
+	}
+	for _, tt := range tests {
+		if got := NameBudget(tt.dir, tt.suffix, tt.profile); got != tt.want {
+			t.Errorf("%s: NameBudget = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// relative directories are measured from where they resolve to
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := NameBudget(".", PartSuffix, ProfileWindows), NameBudget(wd, PartSuffix, ProfileWindows); got != want {
+		t.Errorf("NameBudget(.) = %d, want %d as for %s", got, want, wd)
+	}
+}
+
+func TestFolderBudget(t *testing.T) {
+	short := t.TempDir()
+	deep := deepDir(t, 4000)
+
+	tests := []struct {
+		name    string
+		dir     string
+		profile Profile
+		want    int
+	}{
+		{"posix name limit", short, ProfilePosix, 255},
+		{"posix half of the path", deep, ProfilePosix, (4095 - len(deep) - 1) / 2},
+		{"windows half of the path", short, ProfileWindows, (259 - len(short) - 1) / 2},
+	}
+	for _, tt := range tests {
+		if got := FolderBudget(tt.dir, tt.profile); got != tt.want {
+			t.Errorf("%s: FolderBudget = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestNameBudgetFits creates files of the budgeted length, and one longer, next to a path near the limit.
+func TestNameBudgetFits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the posix path limit is the one of linux")
+	}
+	dir := deepDir(t, 4000)
+	budget := NameBudget(dir, PartSuffix, ProfilePosix)
+
+	name := strings.Repeat("n", budget)
+	if err := os.WriteFile(filepath.Join(dir, name+PartSuffix), nil, 0644); err != nil {
+		t.Fatalf("a %d bytes name within the budget can't be created: %v", budget, err)
+	}
+	err := os.WriteFile(filepath.Join(dir, name+"x"+PartSuffix), nil, 0644)
+	if !errors.Is(err, syscall.ENAMETOOLONG) {
+		t.Errorf("a name over the budget was created, error %v, the budget isn't the limit", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s       string
+		max     int
+		profile Profile
+		want    string
+	}{
+		{"short", 10, ProfilePosix, "short"},
+		{"abcdef", 3, ProfilePosix, "abc"},
+		// é is 2 bytes, it isn't cut in half
+		{"éééé", 5, ProfilePosix, "éé"},
+		{"éééé", 3, ProfileWindows, "ééé"},
+		// an emoji is 2 UTF-16 units
+		{"a😀b", 2, ProfileWindows, "a"},
+		{"a😀b", 3, ProfileFAT32, "a😀"},
+		{"abc", 0, ProfileASCII, ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.max, tt.profile); got != tt.want {
+			t.Errorf("Truncate(%q, %d, %s) = %q, want %q", tt.s, tt.max, tt.profile, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package rootpath
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gosimple/unidecode"
+)
+
+// Profile is a set of file name rules applied the same way on any host,
+// so names written on Linux still work where the files end up being read.
+type Profile string
+
+const (
+	// ProfileNative follows the rules of the host OS (runtime.GOOS).
+	ProfileNative Profile = ""
+	// ProfilePosix only replaces '/' and control characters.
+	ProfilePosix Profile = "posix"
+	// ProfileWindows replaces <>:"/\|?* and avoids reserved names like CON or NUL,
+	// for SMB shares and NTFS/exFAT drives.
+	ProfileWindows Profile = "windows"
+	// ProfileFAT32 adds to ProfileWindows what FAT32 long names can't store:
+	// characters outside of UCS-2 (emoji) are transliterated and names are capped at 255 UTF-16 units.
+	ProfileFAT32 Profile = "fat32"
+	// ProfileASCII transliterates to ASCII and keeps letters, digits, '.', '-' and '_' only.
+	ProfileASCII Profile = "ascii"
+)
+
+// ParseProfile validates a --restrict-filenames value.
+func ParseProfile(name string) (Profile, error) {
+	switch profile := Profile(strings.ToLower(name)); profile {
+	case ProfileNative, ProfilePosix, ProfileWindows, ProfileFAT32, ProfileASCII:
+		return profile, nil
+	default:
+		return "", fmt.Errorf("unknown filename profile %q, expected windows, fat32, ascii or posix", name)
+	}
+}
+
+// SanitizeFilename cleans a single file or folder name, extension included, for the profile.
+func SanitizeFilename(filename string, profile Profile) string {
+	switch profile {
+	case ProfilePosix:
+		return sanitizeUnixFilename(filename)
+	case ProfileWindows:
+		return sanitizeWindowsFilename(filename)
+	case ProfileFAT32:
+		return sanitizeFAT32Filename(filename)
+	case ProfileASCII:
+		return sanitizeASCIIFilename(filename)
+	default:
+		return RemoveInvalidFileNameChars(filename)
+	}
+}
+
+func sanitizeFAT32Filename(filename string) string {
+	// long file names are stored as UCS-2, anything beyond the BMP can't be written
+	filename = strings.Map(func(r rune) rune {
+		if r > 0xFFFF {
+			return -1
+		}
+		return r
+	}, transliterateNonBMP(filename))
+
+	result := sanitizeWindowsFilename(filename)
+
+	// the 255 limit counts UTF-16 units, not bytes
+	ext := filepath.Ext(result)
+	name := []rune(strings.TrimSuffix(result, ext))
+	for len(name) > 0 && utf16Length(string(name)+ext) > WindowsMaxName {
+		name = name[:len(name)-1]
+	}
+	return string(name) + ext
+}
+
+// transliterateNonBMP replaces characters beyond the BMP by their ASCII transliteration,
+// the rest of the name is kept as it is.
+func transliterateNonBMP(s string) string {
+	var result strings.Builder
+	for _, r := range s {
+		if r > 0xFFFF {
+			result.WriteString(unidecode.Unidecode(string(r)))
+		} else {
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
+
+func sanitizeASCIIFilename(filename string) string {
+	ascii := unidecode.Unidecode(filename)
+
+	var result strings.Builder
+	lastUnderscore := false
+	for _, r := range ascii {
+		keep := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-'
+		if keep {
+			result.WriteRune(r)
+			lastUnderscore = false
+			continue
+		}
+		// runs of spaces and punctuation collapse into one underscore
+		if !lastUnderscore {
+			result.WriteRune('_')
+			lastUnderscore = true
+		}
+	}
+
+	// reserved names, dots and length are handled like on windows
+	return sanitizeWindowsFilename(strings.Trim(result.String(), "_"))
+}
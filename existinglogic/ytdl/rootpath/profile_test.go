@@ -0,0 +1,103 @@
+package rootpath
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseProfile(t *testing.T) {
+	tests := []struct {
+		name string
+		want Profile
+		err  bool
+	}{
+		{"", ProfileNative, false},
+		{"posix", ProfilePosix, false},
+		{"windows", ProfileWindows, false},
+		{"Windows", ProfileWindows, false},
+		{"FAT32", ProfileFAT32, false},
+		{"ascii", ProfileASCII, false},
+		{"ntfs", "", true},
+		{"win", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseProfile(tt.name)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("ParseProfile(%q) = %q, %v, want %q, error %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestProfileLimits(t *testing.T) {
+	// the native profile follows the host
+	nativePath, nativeLength := 4095, 6
+	if runtime.GOOS == "windows" {
+		nativePath, nativeLength = 259, 3
+	}
+
+	tests := []struct {
+		profile Profile
+		maxName int
+		maxPath int
+		// length of "é😀"
+		length int
+	}{
+		{ProfilePosix, 255, 4095, 6},
+		{ProfileWindows, 255, 259, 3},
+		{ProfileFAT32, 255, 259, 3},
+		{ProfileASCII, 255, 259, 3},
+		{ProfileNative, 255, nativePath, nativeLength},
+	}
+	for _, tt := range tests {
+		limits := tt.profile.Limits()
+		if limits.MaxName != tt.maxName || limits.MaxPath != tt.maxPath || limits.Length("é😀") != tt.length {
+			t.Errorf("%q: limits %d, %d, length %d, want %d, %d, %d", tt.profile,
+				limits.MaxName, limits.MaxPath, limits.Length("é😀"), tt.maxName, tt.maxPath, tt.length)
+		}
+	}
+}
+
+func TestSanitizeFilenameProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		posix   string
+		windows string
+		fat32   string
+		ascii   string
+	}{
+		{"AC/DC: Back in Black?*.mp4", "AC_DC: Back in Black?*.mp4", "AC_DC_ Back in Black__.mp4", "AC_DC_ Back in Black__.mp4", "AC_DC_Back_in_Black_.mp4"},
+		{"Tab\there<>|.mp4", "Tab_here<>|.mp4", "Tab_here___.mp4", "Tab_here___.mp4", "Tab_here_.mp4"},
+		{"con.txt", "con.txt", "_con.txt", "_con.txt", "_con.txt"},
+		{"LPT1", "LPT1", "_LPT1", "_LPT1", "_LPT1"},
+		{"...", "_", "_", "_", "_"},
+		{"  trailing dots... ", "trailing dots", "trailing dots", "trailing dots", "trailing_dots"},
+		// only fat32 and ascii can't store emoji, ascii transliterates everything
+		{"Café Mix🎵Tape.m4a", "Café Mix🎵Tape.m4a", "Café Mix🎵Tape.m4a", "Café MixTape.m4a", "Cafe_MixTape.m4a"},
+		{"Ünïcödé «quotes».webm", "Ünïcödé «quotes».webm", "Ünïcödé «quotes».webm", "Ünïcödé «quotes».webm", "Unicode_quotes_.webm"},
+	}
+	for _, tt := range tests {
+		for profile, want := range map[Profile]string{ProfilePosix: tt.posix, ProfileWindows: tt.windows, ProfileFAT32: tt.fat32, ProfileASCII: tt.ascii} {
+			if got := SanitizeFilename(tt.name, profile); got != want {
+				t.Errorf("SanitizeFilename(%q, %s) = %q, want %q", tt.name, profile, got, want)
+			}
+		}
+	}
+}
+
+func TestSanitizeFilenameLength(t *testing.T) {
+	names := []string{
+		strings.Repeat("a", 300) + ".mp4",
+		strings.Repeat("é", 300) + ".mp4",
+		strings.Repeat("日本", 150) + ".mp4",
+	}
+	for _, profile := range []Profile{ProfilePosix, ProfileWindows, ProfileFAT32, ProfileASCII} {
+		limits := profile.Limits()
+		for _, name := range names {
+			got := SanitizeFilename(name, profile)
+			if limits.Length(got) > limits.MaxName || !strings.HasSuffix(got, ".mp4") {
+				t.Errorf("%s: %d units long %q, want at most %d ending with .mp4", profile, limits.Length(got), got, limits.MaxName)
+			}
+		}
+	}
+}
@@ -88,11 +88,11 @@ func truncateUnixFilename(filename string, maxBytes int) string {
 	maxNameBytes := maxBytes - len(ext)
 	if maxNameBytes < 1 {
 		// If extension is too long, truncate it
-		return filename[:maxBytes]
+		return truncateUTF8(filename, maxBytes)
 	}
 
 	// Truncate the name part while preserving the extension
-	return nameWithoutExt[:maxNameBytes] + ext
+	return truncateUTF8(nameWithoutExt, maxNameBytes) + ext
 }
 
 // Optional: Additional utility functions that might be useful
@@ -134,7 +134,7 @@ func truncateWindowsFilename(filename string, maxLength int) string {
 		maxNameLength = 1
 	}
 
-	return nameWithoutExt[:maxNameLength] + ext
+	return truncateUTF8(nameWithoutExt, maxNameLength) + ext
 }
 
 func sanitizeWindowsPath(path string) string {
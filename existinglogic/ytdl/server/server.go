@@ -0,0 +1,599 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/downloader"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/queue"
+)
+
+// JobRequest is the body of POST /jobs.
+type JobRequest struct {
+	Link string `json:"link"`
+	// Dst is relative to the server root directory.
+	Dst       string `json:"dst"`
+	Format    string `json:"format"`
+	AudioOnly bool   `json:"audioOnly"`
+	Template  string `json:"template"`
+	VideoOnly bool   `json:"videoOnly"`
+	Author    bool   `json:"author"`
+}
+
+// Job is a single submitted link together with its progress.
+type Job struct {
+	ID      string       `json:"id"`
+	Request JobRequest   `json:"request"`
+	Status  queue.Status `json:"status"`
+	Title   string       `json:"title,omitempty"`
+	Bytes   int64        `json:"bytes"`
+	Total   int64        `json:"total"`
+	Items   []JobItem    `json:"items,omitempty"`
+	Files   []string     `json:"files,omitempty"`
+	Errors  []string     `json:"errors,omitempty"`
+	// Log is the output of the download, its last maxLogLines lines.
+	Log       []string  `json:"log,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	subscribers map[chan downloader.Event]struct{}
+	cancel      context.CancelFunc
+}
+
+// JobItem is the progress of a single video of a job,
+// playlists have one item per entry.
+type JobItem struct {
+	VideoID string               `json:"videoId"`
+	Title   string               `json:"title"`
+	Path    string               `json:"path,omitempty"`
+	Status  downloader.EventType `json:"status"`
+	Bytes   int64                `json:"bytes"`
+	Total   int64                `json:"total"`
+	Error   string               `json:"error,omitempty"`
+}
+
+// snapshot copies the job so it can be used without holding the server lock.
+func (job *Job) snapshot() Job {
+	snapshot := *job
+	snapshot.Items = append([]JobItem(nil), job.Items...)
+	snapshot.Files = append([]string(nil), job.Files...)
+	snapshot.Errors = append([]string(nil), job.Errors...)
+	snapshot.Log = append([]string(nil), job.Log...)
+	snapshot.subscribers = nil
+	snapshot.cancel = nil
+	return snapshot
+}
+
+// item returns the item of the given video, adding it when it's new.
+// Events without a video, like the queued event of the link, have no item.
+func (job *Job) item(event downloader.Event) *JobItem {
+	for i := range job.Items {
+		if job.Items[i].VideoID == event.VideoID {
+			return &job.Items[i]
+		}
+	}
+	job.Items = append(job.Items, JobItem{VideoID: event.VideoID, Title: event.Title})
+	return &job.Items[len(job.Items)-1]
+}
+
+// maxLogLines is how many lines of output a job keeps, older lines are dropped.
+const maxLogLines = 200
+
+// jobLog is the Log of a download, it appends every complete line to the job.
+type jobLog struct {
+	s       *Server
+	id      string
+	partial []byte
+}
+
+func (l *jobLog) Write(p []byte) (int, error) {
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		l.s.log(l.id, string(l.partial[:i]))
+		l.partial = l.partial[i+1:]
+	}
+}
+
+// flush appends what's left of an unterminated last line.
+func (l *jobLog) flush() {
+	if len(l.partial) > 0 {
+		l.s.log(l.id, string(l.partial))
+		l.partial = nil
+	}
+}
+
+// FileInfo is a single entry of GET /files.
+type FileInfo struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// Server runs downloads submitted over HTTP and keeps their state in memory.
+type Server struct {
+	// Root is the directory every download and file listing is relative to.
+	Root string
+	// HTTPClient sends every request of the downloads, http.DefaultClient when nil.
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	nextID  int
+	slots   chan struct{}
+	ctx     context.Context
+	stop    context.CancelFunc
+	running sync.WaitGroup
+}
+
+// New creates a server storing files under root and downloading
+// at most concurrency jobs at the same time.
+func New(root string, concurrency int) *Server {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	return &Server{
+		Root:  root,
+		jobs:  make(map[string]*Job),
+		slots: make(chan struct{}, concurrency),
+		ctx:   ctx,
+		stop:  stop,
+	}
+}
+
+// Close cancels every queued and running job and waits until
+// the running downloads have removed their partial files.
+func (s *Server) Close() {
+	s.stop()
+	s.running.Wait()
+}
+
+// Handler exposes the REST API:
+//   - POST   /jobs             submit a link
+//   - GET    /jobs             list jobs
+//   - GET    /jobs/{id}        job status with progress bytes
+//   - GET    /jobs/{id}/events progress as Server-Sent Events
+//   - DELETE /jobs/{id}        cancel a job, or forget a finished one (?files=1 removes its files)
+//   - GET    /files            list downloaded files
+//   - GET    /files/{path...}  fetch a downloaded file
+//   - DELETE /files/{path...}  remove a downloaded file
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleCreateJob)
+	mux.HandleFunc("GET /jobs", s.handleListJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("GET /jobs/{id}/events", s.handleJobEvents)
+	mux.HandleFunc("DELETE /jobs/{id}", s.handleDeleteJob)
+	mux.HandleFunc("GET /files", s.handleListFiles)
+	mux.HandleFunc("GET /files/{path...}", s.handleGetFile)
+	mux.HandleFunc("DELETE /files/{path...}", s.handleDeleteFile)
+	return mux
+}
+
+func (s *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Link == "" {
+		writeError(w, http.StatusBadRequest, errors.New("link is required"))
+		return
+	}
+	if _, err := s.resolve(req.Dst); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job := s.Submit(req)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Jobs())
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %q not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleJobEvents streams downloader events of the job until it's finished.
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	job, events, unsubscribe, ok := s.subscribe(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %q not found", r.PathValue("id")))
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent(w, "status", job)
+	flusher.Flush()
+	if job.Status != queue.StatusQueued && job.Status != queue.StatusRunning {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-events:
+			if !open {
+				job, _ := s.Job(job.ID)
+				writeEvent(w, "status", job)
+				flusher.Flush()
+				return
+			}
+			writeEvent(w, string(event.Type), event)
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) handleDeleteJob(w http.ResponseWriter, r *http.Request) {
+	removeFiles, _ := strconv.ParseBool(r.URL.Query().Get("files"))
+
+	job, err := s.Delete(r.PathValue("id"), removeFiles)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	files := []FileInfo{}
+	err := filepath.WalkDir(s.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(s.Root, path)
+		files = append(files, FileInfo{
+			Path:    filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+func (s *Server) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	path, err := s.resolve(r.PathValue("path"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+func (s *Server) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
+	path, err := s.resolve(r.PathValue("path"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Submit registers a job and starts downloading it as soon as a slot is free.
+func (s *Server) Submit(req JobRequest) Job {
+	s.mu.Lock()
+	s.nextID++
+	now := time.Now()
+	job := &Job{
+		ID:          strconv.Itoa(s.nextID),
+		Request:     req,
+		Status:      queue.StatusQueued,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		subscribers: make(map[chan downloader.Event]struct{}),
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	job.cancel = cancel
+	s.jobs[job.ID] = job
+	snapshot := job.snapshot()
+	s.running.Add(1)
+	s.mu.Unlock()
+
+	go s.run(ctx, job.ID)
+
+	return snapshot
+}
+
+// Jobs returns a snapshot of all jobs, oldest first.
+func (s *Server) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job.snapshot())
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Job returns a snapshot of a single job.
+func (s *Server) Job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.snapshot(), true
+}
+
+// Delete cancels a queued or running job, interrupting its download.
+// Finished jobs are forgotten, together with the files they produced when removeFiles is set.
+func (s *Server) Delete(id string, removeFiles bool) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("job %q not found", id)
+	}
+
+	if job.Status == queue.StatusQueued || job.Status == queue.StatusRunning {
+		job.Status = queue.StatusCanceled
+		job.UpdatedAt = time.Now()
+		job.cancel()
+		s.closeSubscribers(job)
+		return job.snapshot(), nil
+	}
+
+	if removeFiles {
+		for _, file := range job.Files {
+			os.Remove(file)
+		}
+	}
+	delete(s.jobs, id)
+	return job.snapshot(), nil
+}
+
+// run waits for a free slot and downloads the job until ctx is canceled.
+func (s *Server) run(ctx context.Context, id string) {
+	defer s.running.Done()
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		s.finish(id, []error{ctx.Err()})
+		return
+	}
+	defer func() { <-s.slots }()
+
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	if !ok || job.Status != queue.StatusQueued {
+		s.mu.Unlock()
+		return
+	}
+	job.Status = queue.StatusRunning
+	job.UpdatedAt = time.Now()
+	req := job.Request
+	s.mu.Unlock()
+
+	outputDir, _ := s.resolve(req.Dst)
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		s.finish(id, []error{err})
+		return
+	}
+
+	log := &jobLog{s: s, id: id}
+	errs := downloader.DownloadLink(ctx, req.Link, downloader.Options{
+		OutputDir:     outputDir,
+		VideoOnly:     req.VideoOnly,
+		IncludeAuthor: req.Author,
+		Format:        req.Format,
+		AudioOnly:     req.AudioOnly,
+		Template:      req.Template,
+		OnEvent: func(event downloader.Event) {
+			s.update(id, event)
+		},
+		Log:        log,
+		Registry:   extractor.NewDefaultRegistry(s.HTTPClient),
+		HTTPClient: s.HTTPClient,
+	})
+	log.flush()
+	s.finish(id, errs)
+}
+
+// update applies a downloader event to the job and forwards it to subscribers.
+func (s *Server) update(id string, event downloader.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.Status != queue.StatusRunning {
+		return
+	}
+
+	switch event.Type {
+	case downloader.EventMetadata:
+		job.Title = event.Title
+		job.Bytes = 0
+		job.Total = event.Total
+	case downloader.EventProgress:
+		job.Bytes = event.Bytes
+	case downloader.EventDone:
+		job.Bytes = event.Bytes
+		job.Files = append(job.Files, event.Path)
+	}
+	if event.VideoID != "" {
+		item := job.item(event)
+		item.Status = event.Type
+		item.Path = event.Path
+		item.Bytes = event.Bytes
+		item.Total = event.Total
+		item.Error = event.Error
+	}
+	job.UpdatedAt = time.Now()
+
+	for events := range job.subscribers {
+		// slow clients miss intermediate events rather than blocking the download
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// log appends a line of output to the job.
+func (s *Server) log(id, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+	job.Log = append(job.Log, strings.TrimRight(line, "\r"))
+	if len(job.Log) > maxLogLines {
+		job.Log = append(job.Log[:0], job.Log[len(job.Log)-maxLogLines:]...)
+	}
+}
+
+func (s *Server) finish(id string, errs []error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || (job.Status != queue.StatusQueued && job.Status != queue.StatusRunning) {
+		return
+	}
+	job.cancel()
+
+	canceled := false
+	for _, err := range errs {
+		job.Errors = append(job.Errors, err.Error())
+		canceled = canceled || errors.Is(err, context.Canceled)
+	}
+	if canceled {
+		job.Status = queue.StatusCanceled
+	} else if len(errs) > 0 {
+		job.Status = queue.StatusFailed
+	} else {
+		job.Status = queue.StatusDone
+	}
+	job.UpdatedAt = time.Now()
+	s.closeSubscribers(job)
+}
+
+// subscribe returns the current job state and a channel of its next events.
+// The channel is closed once the job is finished.
+func (s *Server) subscribe(id string) (Job, <-chan downloader.Event, func(), bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, nil, nil, false
+	}
+
+	events := make(chan downloader.Event, 16)
+	if job.Status == queue.StatusQueued || job.Status == queue.StatusRunning {
+		job.subscribers[events] = struct{}{}
+	} else {
+		close(events)
+	}
+
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(job.subscribers, events)
+	}
+	return job.snapshot(), events, unsubscribe, true
+}
+
+// closeSubscribers must be called with s.mu held.
+func (s *Server) closeSubscribers(job *Job) {
+	for events := range job.subscribers {
+		close(events)
+		delete(job.subscribers, events)
+	}
+}
+
+// resolve maps a client supplied relative path onto the root directory,
+// refusing anything that would escape it.
+func (s *Server) resolve(rel string) (string, error) {
+	if rel == "" {
+		return s.Root, nil
+	}
+	rel = filepath.FromSlash(rel)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %q is outside of the server root", rel)
+	}
+	return filepath.Join(s.Root, rel), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeEvent(w http.ResponseWriter, name string, v any) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
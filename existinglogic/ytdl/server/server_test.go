@@ -0,0 +1,296 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/queue"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/testserver"
+)
+
+func newTestServer(t *testing.T, concurrency int) (*Server, *httptest.Server, *testserver.Server) {
+	t.Helper()
+	youtube := testserver.New()
+	t.Cleanup(youtube.Close)
+
+	s := New(t.TempDir(), concurrency)
+	s.HTTPClient = youtube.Client()
+	t.Cleanup(s.Close)
+
+	api := httptest.NewServer(s.Handler())
+	t.Cleanup(api.Close)
+	return s, api, youtube
+}
+
+func do(t *testing.T, method, url string, body any, want int, v any) {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, err := http.NewRequest(method, url, &reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != want {
+		t.Fatalf("%s %s: got status %d, want %d", method, url, resp.StatusCode, want)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// waitJob polls the job until it's no longer queued or running.
+func waitJob(t *testing.T, api *httptest.Server, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var job Job
+		do(t, http.MethodGet, api.URL+"/jobs/"+id, nil, http.StatusOK, &job)
+		if job.Status != queue.StatusQueued && job.Status != queue.StatusRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still %s", id, job.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSubmitAndStatus(t *testing.T) {
+	s, api, youtube := newTestServer(t, 1)
+	media := bytes.Repeat([]byte("0123456789"), 10000)
+	youtube.AddVideo(testserver.Video{ID: "aaaaaaaaaaa", Title: "First Video", Author: "Someone", Duration: 10, Media: media})
+
+	var job Job
+	do(t, http.MethodPost, api.URL+"/jobs", JobRequest{Link: "https://www.youtube.com/watch?v=aaaaaaaaaaa", Dst: "music"}, http.StatusAccepted, &job)
+	if job.ID == "" || job.Status != queue.StatusQueued {
+		t.Fatalf("submitted job = %+v, want a queued job with an id", job)
+	}
+
+	job = waitJob(t, api, job.ID)
+	if job.Status != queue.StatusDone {
+		t.Fatalf("status = %s, errors = %v, want done", job.Status, job.Errors)
+	}
+	if job.Title != "First Video" || job.Bytes != int64(len(media)) || job.Total != int64(len(media)) {
+		t.Errorf("title, bytes, total = %q, %d, %d, want %q, %d, %d", job.Title, job.Bytes, job.Total, "First Video", len(media), len(media))
+	}
+	if len(job.Files) != 1 {
+		t.Fatalf("files = %v, want one file", job.Files)
+	}
+	if dir := filepath.Dir(job.Files[0]); dir != filepath.Join(s.Root, "music") {
+		t.Errorf("file saved in %s, want %s", dir, filepath.Join(s.Root, "music"))
+	}
+	info, err := os.Stat(job.Files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(media)) {
+		t.Errorf("file size = %d, want %d", info.Size(), len(media))
+	}
+	if len(job.Log) == 0 {
+		t.Error("the output of the download isn't in the job log")
+	}
+	// the queued event of the link has no video, it makes no item
+	if len(job.Items) != 1 || job.Items[0].VideoID != "aaaaaaaaaaa" || job.Items[0].Status != "done" {
+		t.Errorf("items = %+v, want the video done", job.Items)
+	}
+
+	var jobs []Job
+	do(t, http.MethodGet, api.URL+"/jobs", nil, http.StatusOK, &jobs)
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("jobs = %+v, want only job %s", jobs, job.ID)
+	}
+}
+
+func TestSubmitFailure(t *testing.T) {
+	_, api, youtube := newTestServer(t, 1)
+	youtube.AddVideo(testserver.Video{ID: "bbbbbbbbbbb", Title: "Private", Status: "LOGIN_REQUIRED", Reason: "This video is private"})
+
+	var job Job
+	do(t, http.MethodPost, api.URL+"/jobs", JobRequest{Link: "https://www.youtube.com/watch?v=bbbbbbbbbbb"}, http.StatusAccepted, &job)
+	job = waitJob(t, api, job.ID)
+	if job.Status != queue.StatusFailed || len(job.Errors) == 0 {
+		t.Errorf("status = %s, errors = %v, want failed with an error", job.Status, job.Errors)
+	}
+}
+
+func TestSubmitInvalid(t *testing.T) {
+	_, api, _ := newTestServer(t, 1)
+
+	for _, req := range []JobRequest{
+		{},
+		{Link: "https://www.youtube.com/watch?v=aaaaaaaaaaa", Dst: "../outside"},
+		{Link: "https://www.youtube.com/watch?v=aaaaaaaaaaa", Dst: "/tmp"},
+	} {
+		do(t, http.MethodPost, api.URL+"/jobs", req, http.StatusBadRequest, nil)
+	}
+	do(t, http.MethodGet, api.URL+"/jobs/42", nil, http.StatusNotFound, nil)
+}
+
+func TestCancel(t *testing.T) {
+	s, api, youtube := newTestServer(t, 1)
+	youtube.AddVideo(testserver.Video{ID: "aaaaaaaaaaa", Title: "First Video"})
+
+	// hold the only slot so the job stays queued
+	s.slots <- struct{}{}
+	var job Job
+	do(t, http.MethodPost, api.URL+"/jobs", JobRequest{Link: "https://www.youtube.com/watch?v=aaaaaaaaaaa"}, http.StatusAccepted, &job)
+
+	do(t, http.MethodDelete, api.URL+"/jobs/"+job.ID, nil, http.StatusOK, &job)
+	if job.Status != queue.StatusCanceled {
+		t.Fatalf("status after cancel = %s, want canceled", job.Status)
+	}
+	<-s.slots
+
+	job = waitJob(t, api, job.ID)
+	if job.Status != queue.StatusCanceled || len(job.Files) != 0 {
+		t.Errorf("job = %+v, want canceled without files", job)
+	}
+
+	// finished jobs are forgotten by a second delete
+	do(t, http.MethodDelete, api.URL+"/jobs/"+job.ID, nil, http.StatusOK, nil)
+	do(t, http.MethodGet, api.URL+"/jobs/"+job.ID, nil, http.StatusNotFound, nil)
+	do(t, http.MethodDelete, api.URL+"/jobs/"+job.ID, nil, http.StatusNotFound, nil)
+}
+
+// sseEvent is an event read from a Server-Sent Events stream.
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvents reads the events of body until the stream ends.
+func readEvents(t *testing.T, body io.Reader) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var event sseEvent
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, event)
+			event = sseEvent{}
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestJobEvents(t *testing.T) {
+	s, api, youtube := newTestServer(t, 1)
+	youtube.AddVideo(testserver.Video{ID: "aaaaaaaaaaa", Title: "First Video", Media: bytes.Repeat([]byte("x"), 5000)})
+
+	// the job waits for the slot until the stream is open
+	s.slots <- struct{}{}
+	var job Job
+	do(t, http.MethodPost, api.URL+"/jobs", JobRequest{Link: "https://www.youtube.com/watch?v=aaaaaaaaaaa"}, http.StatusAccepted, &job)
+
+	resp, err := http.Get(api.URL + "/jobs/" + job.ID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	<-s.slots
+
+	events := readEvents(t, resp.Body)
+	var names []string
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	if len(events) < 4 || events[0].name != "status" || events[len(events)-1].name != "status" {
+		t.Fatalf("events = %q, want the download between two status events", names)
+	}
+	for _, name := range []string{"metadata", "done"} {
+		if !strings.Contains(strings.Join(names, " "), name) {
+			t.Errorf("events = %q, want a %s event", names, name)
+		}
+	}
+	var last Job
+	if err := json.Unmarshal([]byte(events[len(events)-1].data), &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.ID != job.ID || last.Status != queue.StatusDone {
+		t.Errorf("last status = %+v, want job %s done", last, job.ID)
+	}
+
+	// a finished job sends its status and closes the stream
+	resp, err = http.Get(api.URL + "/jobs/" + job.ID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if events := readEvents(t, resp.Body); len(events) != 1 || events[0].name != "status" {
+		t.Errorf("events of a finished job = %+v, want its status", events)
+	}
+	do(t, http.MethodGet, api.URL+"/jobs/42/events", nil, http.StatusNotFound, nil)
+}
+
+func TestFiles(t *testing.T) {
+	s, api, _ := newTestServer(t, 1)
+	if err := os.MkdirAll(filepath.Join(s.Root, "music"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Root, "music", "song.mp3"), []byte("song"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Root, "video.mp4"), []byte("video!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []FileInfo
+	do(t, http.MethodGet, api.URL+"/files", nil, http.StatusOK, &files)
+	if len(files) != 2 || files[0].Path != "music/song.mp3" || files[0].Size != 4 || files[1].Path != "video.mp4" || files[1].Size != 6 {
+		t.Errorf("files = %+v", files)
+	}
+
+	resp, err := http.Get(api.URL + "/files/music/song.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != "song" {
+		t.Errorf("GET file = %d %q, want the song", resp.StatusCode, data)
+	}
+	do(t, http.MethodGet, api.URL+"/files/missing.mp3", nil, http.StatusNotFound, nil)
+	do(t, http.MethodGet, api.URL+"/files/..%2Fsecret", nil, http.StatusBadRequest, nil)
+
+	do(t, http.MethodDelete, api.URL+"/files/music/song.mp3", nil, http.StatusNoContent, nil)
+	if _, err := os.Stat(filepath.Join(s.Root, "music", "song.mp3")); !os.IsNotExist(err) {
+		t.Errorf("deleted file is still there: %v", err)
+	}
+	do(t, http.MethodDelete, api.URL+"/files/music/song.mp3", nil, http.StatusNotFound, nil)
+	do(t, http.MethodDelete, api.URL+"/files/..%2Fsecret", nil, http.StatusBadRequest, nil)
+
+	do(t, http.MethodGet, api.URL+"/files", nil, http.StatusOK, &files)
+	if len(files) != 1 || files[0].Path != "video.mp4" {
+		t.Errorf("files after delete = %+v", files)
+	}
+}
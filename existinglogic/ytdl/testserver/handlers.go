@@ -0,0 +1,297 @@
+package testserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+)
+
+// innertubeRequest is the part of the player and browse requests the fake reads.
+type innertubeRequest struct {
+	VideoID      string `json:"videoId"`
+	BrowseID     string `json:"browseId"`
+	Continuation string `json:"continuation"`
+}
+
+func (srv *Server) handlePlayer(w http.ResponseWriter, r *http.Request) {
+	var req innertubeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, srv.playerResponse(req.VideoID))
+}
+
+func (srv *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(srv.playerResponse(r.URL.Query().Get("v")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>%s - YouTube</title></head><body>"+
+		"<script>var ytInitialPlayerResponse = %s;</script></body></html>",
+		html.EscapeString(r.URL.Query().Get("v")), data)
+}
+
+// playerResponse describes the video like YouTube does, unknown ids get an ERROR status.
+func (srv *Server) playerResponse(id string) models.PlayerResponse {
+	var response models.PlayerResponse
+
+	v, ok := srv.video(id)
+	if !ok {
+		response.PlayabilityStatus.Status = "ERROR"
+		response.PlayabilityStatus.Reason = "Video unavailable"
+		return response
+	}
+
+	response.PlayabilityStatus.Status = v.Status
+	response.PlayabilityStatus.Reason = v.Reason
+	response.PlayabilityStatus.PlayableInEmbed = v.Status == "OK"
+	response.VideoDetails = models.VideoDetails{
+		VideoId:          v.ID,
+		Title:            v.Title,
+		LengthSeconds:    strconv.Itoa(v.Duration),
+		ChannelId:        v.ChannelID,
+		ShortDescription: v.Description,
+		Author:           v.Author,
+		ViewCount:        "1",
+	}
+	if v.Status != "OK" {
+		return response
+	}
+
+	size := strconv.Itoa(len(v.Media))
+	response.StreamingData.ExpiresInSeconds = "21540"
+	response.StreamingData.Formats = []models.PlayerFormat{{
+		Itag:          18,
+		Url:           srv.mediaURL(v.ID, 18),
+		MimeType:      `video/mp4; codecs="avc1.42001E, mp4a.40.2"`,
+		Bitrate:       500000,
+		Width:         640,
+		Height:        360,
+		ContentLength: size,
+		Quality:       "medium",
+		QualityLabel:  "360p",
+		FPS:           30,
+		AudioQuality:  "AUDIO_QUALITY_LOW",
+		AudioChannels: 2,
+	}}
+	response.StreamingData.AdaptiveFormats = []models.PlayerFormat{{
+		Itag:            140,
+		Url:             srv.mediaURL(v.ID, 140),
+		MimeType:        `audio/mp4; codecs="mp4a.40.2"`,
+		Bitrate:         130000,
+		ContentLength:   size,
+		Quality:         "tiny",
+		AudioQuality:    "AUDIO_QUALITY_MEDIUM",
+		AudioSampleRate: "44100",
+		AudioChannels:   2,
+	}}
+	return response
+}
+
+func (srv *Server) mediaURL(id string, itag int) string {
+	return fmt.Sprintf("%s/videoplayback?id=%s&itag=%d", srv.URL, id, itag)
+}
+
+// handleMedia serves the media of a video. Ranges are honored both as a Range header
+// and as the range=<start>-<end> query parameter used by googlevideo.com.
+func (srv *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	v, ok := srv.video(r.URL.Query().Get("id"))
+	if !ok || v.Status != "OK" {
+		http.NotFound(w, r)
+		return
+	}
+
+	media := v.Media
+	if value := r.URL.Query().Get("range"); value != "" {
+		from, to, ok := parseRange(value, int64(len(media)))
+		if !ok {
+			http.Error(w, "invalid range", http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		media = media[from : to+1]
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(media))
+}
+
+// parseRange parses "<start>-<end>", end included and capped to the size.
+func parseRange(value string, size int64) (int64, int64, bool) {
+	start, end, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	from, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || from < 0 || from >= size {
+		return 0, 0, false
+	}
+	to := size - 1
+	if end != "" {
+		if to, err = strconv.ParseInt(end, 10, 64); err != nil || to < from {
+			return 0, 0, false
+		}
+		to = min(to, size-1)
+	}
+	return from, to, true
+}
+
+func (srv *Server) handleBrowse(w http.ResponseWriter, r *http.Request) {
+	var req innertubeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// continuations are "<playlist id>:<offset>"
+	if req.Continuation != "" {
+		id, offset, _ := strings.Cut(req.Continuation, ":")
+		start, _ := strconv.Atoi(offset)
+		p, ok := srv.playlist(id)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, map[string]any{
+			"onResponseReceivedActions": []any{map[string]any{
+				"appendContinuationItemsAction": map[string]any{
+					"continuationItems": srv.playlistItems(p, start),
+				},
+			}},
+		})
+		return
+	}
+
+	p, ok := srv.playlist(strings.TrimPrefix(req.BrowseID, "VL"))
+	if !ok {
+		writeJSON(w, playlistError("The playlist does not exist."))
+		return
+	}
+	writeJSON(w, srv.browseResponse(p))
+}
+
+func (srv *Server) handlePlaylist(w http.ResponseWriter, r *http.Request) {
+	p, ok := srv.playlist(r.URL.Query().Get("list"))
+	var initialData any = playlistError("The playlist does not exist.")
+	if ok {
+		initialData = srv.browseResponse(p)
+	}
+	data, err := json.Marshal(initialData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>%s - YouTube</title></head><body>"+
+		"<script>var ytInitialData = %s;</script></body></html>",
+		html.EscapeString(p.Title), data)
+}
+
+// browseResponse is the first page of a playlist as returned by the browse API.
+func (srv *Server) browseResponse(p Playlist) map[string]any {
+	return map[string]any{
+		"header": map[string]any{
+			"playlistHeaderRenderer": map[string]any{
+				"playlistId":      p.ID,
+				"title":           runs(p.Title),
+				"descriptionText": runs(p.Description),
+				"ownerText":       runs(p.Author),
+			},
+		},
+		// the web client has the title and owner in the sidebar too
+		"sidebar": map[string]any{
+			"playlistSidebarRenderer": map[string]any{
+				"items": []any{
+					map[string]any{
+						"playlistSidebarPrimaryInfoRenderer": map[string]any{
+							"title":       runs(p.Title),
+							"description": runs(p.Description),
+						},
+					},
+					map[string]any{
+						"playlistSidebarSecondaryInfoRenderer": map[string]any{
+							"videoOwner": map[string]any{
+								"videoOwnerRenderer": map[string]any{"title": runs(p.Author)},
+							},
+						},
+					},
+				},
+			},
+		},
+		"contents": map[string]any{
+			"singleColumnBrowseResultsRenderer": map[string]any{
+				"tabs": []any{map[string]any{
+					"tabRenderer": map[string]any{
+						"content": map[string]any{
+							"sectionListRenderer": map[string]any{
+								"contents": []any{map[string]any{
+									"playlistVideoListRenderer": map[string]any{
+										"contents": srv.playlistItems(p, 0),
+									},
+								}},
+							},
+						},
+					},
+				}},
+			},
+		},
+	}
+}
+
+// playlistItems is a page of entries from start, followed by a continuation when more are left.
+func (srv *Server) playlistItems(p Playlist, start int) []any {
+	end := min(start+max(srv.PageSize, 1), len(p.VideoIDs))
+
+	var items []any
+	for _, id := range p.VideoIDs[min(start, end):end] {
+		v, _ := srv.video(id)
+		items = append(items, map[string]any{
+			"playlistVideoRenderer": map[string]any{
+				"videoId":         id,
+				"title":           runs(v.Title),
+				"shortBylineText": runs(v.Author),
+				"lengthSeconds":   strconv.Itoa(v.Duration),
+				"thumbnail":       map[string]any{"thumbnails": []any{}},
+			},
+		})
+	}
+	if end < len(p.VideoIDs) {
+		items = append(items, map[string]any{
+			"continuationItemRenderer": map[string]any{
+				"continuationEndpoint": map[string]any{
+					"continuationCommand": map[string]any{"token": fmt.Sprintf("%s:%d", p.ID, end)},
+				},
+			},
+		})
+	}
+	return items
+}
+
+func playlistError(message string) map[string]any {
+	return map[string]any{
+		"alerts": []any{map[string]any{
+			"alertRenderer": map[string]any{"type": "ERROR", "text": runs(message)},
+		}},
+	}
+}
+
+func runs(text string) map[string]any {
+	return map[string]any{"runs": []any{map[string]any{"text": text}}}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,159 @@
+// Package testserver is a fake YouTube for end-to-end tests that run offline.
+// It serves watch pages, the innertube player and browse API used by github.com/kkdai/youtube,
+// playlist pages and media files honoring byte ranges, for the videos and playlists added to it.
+//
+// Every client of ytdl takes an *http.Client, Server.Client returns one sending
+// youtube.com, youtu.be and googlevideo.com requests to the fake:
+//
+//	srv := testserver.New()
+//	defer srv.Close()
+//	srv.AddVideo(testserver.Video{ID: "dQw4w9WgXcQ", Title: "Test"})
+//	client := ytdl.New(ytdl.Options{HTTPClient: srv.Client()})
+package testserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Video is a video served by the fake.
+type Video struct {
+	ID          string
+	Title       string
+	Author      string
+	ChannelID   string
+	Description string
+	// Duration in seconds.
+	Duration int
+	// Media is served for every format of the video, 64 KiB of filler when nil.
+	Media []byte
+	// Status is the playability status, "OK" when empty.
+	// "LOGIN_REQUIRED", "UNPLAYABLE" or "ERROR" make the video unavailable, explained by Reason.
+	Status string
+	Reason string
+}
+
+// Playlist is a playlist served by the fake, its videos have to be added too.
+type Playlist struct {
+	ID          string
+	Title       string
+	Author      string
+	Description string
+	VideoIDs    []string
+}
+
+// Server is a running fake YouTube.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	videos    map[string]Video
+	playlists map[string]Playlist
+	// PageSize is how many playlist entries a browse response holds before a continuation.
+	PageSize int
+}
+
+// youtubeHosts are the hosts Client sends to the fake.
+var youtubeHosts = []string{"youtube.com", "youtu.be", "googlevideo.com", "ytimg.com"}
+
+// New starts a fake with no videos, Close stops it.
+func New() *Server {
+	srv := &Server{
+		videos:    make(map[string]Video),
+		playlists: make(map[string]Playlist),
+		PageSize:  100,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /youtubei/v1/player", srv.handlePlayer)
+	mux.HandleFunc("POST /youtubei/v1/browse", srv.handleBrowse)
+	mux.HandleFunc("GET /watch", srv.handleWatch)
+	mux.HandleFunc("GET /playlist", srv.handlePlaylist)
+	mux.HandleFunc("GET /videoplayback", srv.handleMedia)
+	srv.Server = httptest.NewServer(mux)
+	return srv
+}
+
+// AddVideo serves v, replacing a video of the same ID.
+func (srv *Server) AddVideo(v Video) {
+	if v.Status == "" {
+		v.Status = "OK"
+	}
+	if v.Media == nil {
+		v.Media = bytes.Repeat([]byte(v.ID+"\n"), 64<<10/(len(v.ID)+1))
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.videos[v.ID] = v
+}
+
+// AddPlaylist serves p, replacing a playlist of the same ID.
+func (srv *Server) AddPlaylist(p Playlist) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.playlists[p.ID] = p
+}
+
+func (srv *Server) video(id string) (Video, bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	v, ok := srv.videos[id]
+	return v, ok
+}
+
+func (srv *Server) playlist(id string) (Playlist, bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	p, ok := srv.playlists[id]
+	return p, ok
+}
+
+// Client returns an HTTP client sending YouTube requests to the fake, other requests go out as usual.
+// It has no cookie jar, set one to test signed in downloads.
+func (srv *Server) Client() *http.Client {
+	return &http.Client{Transport: srv.Transport(http.DefaultTransport)}
+}
+
+// Transport wraps base so YouTube requests reach the fake instead.
+// youtu.be/<id> links are turned into watch pages on the way.
+func (srv *Server) Transport(base http.RoundTripper) http.RoundTripper {
+	target, _ := url.Parse(srv.URL)
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		if !isYouTubeHost(req.URL.Hostname()) {
+			return base.RoundTrip(req)
+		}
+
+		req = req.Clone(req.Context())
+		if strings.EqualFold(req.URL.Hostname(), "youtu.be") {
+			query := req.URL.Query()
+			query.Set("v", strings.Trim(req.URL.Path, "/"))
+			req.URL.Path = "/watch"
+			req.URL.RawQuery = query.Encode()
+		}
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		req.Host = target.Host
+		return base.RoundTrip(req)
+	})
+}
+
+func isYouTubeHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, suffix := range youtubeHosts {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
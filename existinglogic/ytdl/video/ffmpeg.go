@@ -1,49 +1,57 @@
 package video
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"path"
+	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/gosimple/slug"
-	"github.com/gosuri/uiprogress"
-)
 
-// ConvertVideoToAudio converts video to mp3 and saves in dstDir.
-func ConvertVideoToAudio(video *Video, dstDir string, results chan<- ChannelMessage) {
-	// Get bar with steps.
-	var steps = []string{"Converting to audio", "DONE!"}
-	bar := uiprogress.AddBar(len(steps))
-	bar.Width = progressBarWidth
-	bar.AppendFunc(func(b *uiprogress.Bar) string {
-		return fmt.Sprintf("%v - %v", (*video).url, steps[b.Current()-1])
-	})
-	bar.PrependFunc(func(b *uiprogress.Bar) string {
-		return fmt.Sprintf("[Convert]    ")
-	})
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/postprocess"
+)
 
-	// Prepare ffmpeg cmd.
-	bar.Incr()
-	time.Sleep(time.Millisecond * 50)
+// ConvertVideoToAudio converts video to mp3 and saves in dstDir, then runs PostProcess on it.
+// ffmpeg is killed when ctx is canceled and the partial mp3 is removed.
+// The saved mp3 is kept when a PostProcess step fails.
+func ConvertVideoToAudio(ctx context.Context, video *Video, dstDir string, results chan<- ChannelMessage) {
+	Progress.Stage((*video).url, "converting to audio")
 	inputPath, _ := filepath.Abs(video.File.Name())
-	outputPath := path.Join(dstDir, fmt.Sprintf("%v.mp3", slug.Make(video.name)))
-	// TODO: Think if it's possible (it is possible) to tune a command.
-	cmd := exec.Command(
-		"ffmpeg",
-		"-i",
-		inputPath,
-		outputPath,
-	)
+	item := &postprocess.Item{
+		Path: inputPath,
+		Video: models.VideoInfo{
+			ID:       video.id,
+			Url:      video.url,
+			Title:    video.name,
+			Duration: video.duration.Seconds(),
+		},
+		Client: HTTPClient,
+		Log:    Log,
+		Output: Output,
+		Stage: func(stage string) {
+			Progress.Stage((*video).url, stage)
+		},
+	}
+	convert := postprocess.Chain{
+		&postprocess.ExtractAudio{Format: "mp3", Keep: true},
+		&postprocess.Move{Dir: dstDir, Filename: fmt.Sprintf("%v.mp3", slug.Make(video.name))},
+	}
+	if err := convert.Run(ctx, item); err != nil {
+		// the mp3 is left next to the video when it couldn't be moved
+		if item.Path != inputPath {
+			os.Remove(item.Path)
+		}
+		results <- ChannelMessage{Error: err, Link: (*video).url}
+		return
+	}
+	(*video).AudioFilePath = item.Path
 
-	// Run ffmpeg.
-	if err := cmd.Run(); err != nil {
-		results <- ChannelMessage{Error: err}
+	if err := PostProcess.Run(ctx, item); err != nil {
+		results <- ChannelMessage{Error: err, Link: (*video).url}
 		return
 	}
-	(*video).AudioFilePath = outputPath
-	bar.Incr()
-	time.Sleep(time.Millisecond * 50)
-	results <- ChannelMessage{Result: video}
+	(*video).AudioFilePath = item.Path
+	Progress.Done((*video).url)
+	results <- ChannelMessage{Result: video, Link: (*video).url}
 }
@@ -0,0 +1,65 @@
+package video
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/postprocess"
+)
+
+func TestConvertVideoToAudio(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	// copies the input to the output
+	ffmpeg := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nprev=\"\"\nfor a in \"$@\"; do\n\t[ \"$prev\" = \"-i\" ] && in=\"$a\"\n\tprev=\"$a\"\ndone\ncp \"$in\" \"$prev\"\n"
+	if err := os.WriteFile(ffmpeg, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	previous := postprocess.FFmpeg
+	postprocess.FFmpeg = ffmpeg
+	t.Cleanup(func() { postprocess.FFmpeg, PostProcess, Log = previous, nil, os.Stdout })
+	Log = io.Discard
+
+	tests := []struct {
+		name        string
+		postProcess postprocess.Chain
+		ok          bool
+	}{
+		{"no steps", nil, true},
+		{"exec", postprocess.Chain{&postprocess.Exec{Template: "true"}}, true},
+		// the mp3 is already in dstDir, it's still wanted
+		{"failing exec", postprocess.Chain{&postprocess.Exec{Template: "false"}}, false},
+	}
+	for _, tt := range tests {
+		input, err := os.CreateTemp(t.TempDir(), "video-*.mp4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		input.WriteString("media")
+		input.Close()
+		dstDir := t.TempDir()
+		PostProcess = tt.postProcess
+
+		video := &Video{url: "https://youtu.be/aaaaaaaaaaa", name: "My Song", File: input}
+		results := make(chan ChannelMessage, 1)
+		ConvertVideoToAudio(context.Background(), video, dstDir, results)
+		msg := <-results
+
+		if (msg.Error == nil) != tt.ok {
+			t.Errorf("%s: error = %v, want ok %v", tt.name, msg.Error, tt.ok)
+		}
+		mp3 := filepath.Join(dstDir, "my-song.mp3")
+		if data, err := os.ReadFile(mp3); err != nil || string(data) != "media" {
+			t.Errorf("%s: %s holds %q, %v", tt.name, mp3, data, err)
+		}
+		if video.AudioFilePath != mp3 {
+			t.Errorf("%s: AudioFilePath = %s, want %s", tt.name, video.AudioFilePath, mp3)
+		}
+	}
+}
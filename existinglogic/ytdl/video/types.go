@@ -3,6 +3,7 @@ package video
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
 // ChannelMessage used to exchange between a main thread and goroutines.
@@ -42,13 +43,35 @@ func (e *ErrorFetchPlaybackURL) Error() string {
 // Video internal video object.
 type Video struct {
 	url           string
+	id            string
 	streamUrl     string
 	name          string
 	mimeType      string
+	duration      time.Duration
 	File          *os.File
 	AudioFilePath string
 }
 
+// URL is the link of the video, cleaned of everything but its id.
+func (v Video) URL() string {
+	return v.url
+}
+
+// ID is the YouTube id of the video.
+func (v Video) ID() string {
+	return v.id
+}
+
+// Title is set by FetchMetadata.
+func (v Video) Title() string {
+	return v.name
+}
+
+// Duration is set by FetchMetadata.
+func (v Video) Duration() time.Duration {
+	return v.duration
+}
+
 func (v Video) String() string {
 	return fmt.Sprintf(
 		"<name=%q url=%q hasStream=%v mime=%v file=%v audio=%v>",
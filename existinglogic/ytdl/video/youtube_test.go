@@ -0,0 +1,80 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateLinks(t *testing.T) {
+	tests := []struct {
+		link string
+		ok   bool
+	}{
+		{"https://youtu.be/aaaaaaaaaaa", true},
+		{"https://www.youtube.com/watch?v=aaaaaaaaaaa", true},
+		// the download command handles them, this package can't
+		{"https://example.com/video.mp4", false},
+		{"https://vimeo.com/123", false},
+		{"youtu.be/aaaaaaaaaaa", false},
+	}
+	for _, tt := range tests {
+		errs := ValidateLinks([]string{tt.link})
+		if (len(errs) == 0) != tt.ok {
+			t.Errorf("ValidateLinks(%s) = %v, want ok %v", tt.link, errs, tt.ok)
+		}
+		var bad *ErrorBadLink
+		for _, err := range errs {
+			if !errors.As(err, &bad) {
+				t.Errorf("ValidateLinks(%s) error %v isn't an ErrorBadLink", tt.link, err)
+			}
+		}
+	}
+}
+
+func TestFetchVideo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/forbidden" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("media"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"/media", true},
+		{"/forbidden", false},
+	}
+	for _, tt := range tests {
+		video := &Video{url: "https://youtu.be/aaaaaaaaaaa", streamUrl: srv.URL + tt.path}
+		results := make(chan ChannelMessage, 1)
+		FetchVideo(context.Background(), video, results)
+		msg := <-results
+
+		if (msg.Error == nil) != tt.ok {
+			t.Errorf("%s: FetchVideo error = %v, want ok %v", tt.path, msg.Error, tt.ok)
+		}
+		data, err := os.ReadFile(video.File.Name())
+		if tt.ok && (err != nil || string(data) != "media") {
+			t.Errorf("%s: temp file holds %q, %v", tt.path, data, err)
+		}
+		if !tt.ok {
+			if !strings.Contains(fmt.Sprint(msg.Error), "status 403") {
+				t.Errorf("%s: error %v doesn't tell the status", tt.path, msg.Error)
+			}
+			if !os.IsNotExist(err) {
+				t.Errorf("%s: the temp file of a failed download is left behind", tt.path)
+			}
+		}
+		os.Remove(video.File.Name())
+	}
+}
@@ -0,0 +1,136 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+async function api(method, path, body) {
+  const resp = await fetch("api" + path, {
+    method: method,
+    headers: body ? { "Content-Type": "application/json" } : {},
+    body: body ? JSON.stringify(body) : undefined,
+  });
+  const data = resp.status === 204 ? null : await resp.json();
+  if (!resp.ok) {
+    throw new Error(data && data.error ? data.error : resp.statusText);
+  }
+  return data;
+}
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  Object.assign(node, attrs || {});
+  for (const child of children) {
+    node.append(child);
+  }
+  return node;
+}
+
+function bytes(n) {
+  const units = ["B", "KB", "MB", "GB"];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) {
+    n /= 1024;
+    i++;
+  }
+  return n.toFixed(i === 0 ? 0 : 1) + " " + units[i];
+}
+
+// bar mirrors the terminal progress bars: percent when the size is known, bytes otherwise.
+function bar(item) {
+  const percent = item.total > 0 ? Math.min(100, (100 * item.bytes) / item.total) : 0;
+  const label = item.total > 0 ? percent.toFixed(0) + "%" : bytes(item.bytes);
+  return el("span", {},
+    el("div", { className: "bar" }, el("div", { style: `width: ${percent}%` })),
+    " " + label);
+}
+
+async function loadFormats() {
+  const link = $("link").value;
+  if (!link) {
+    return;
+  }
+  $("message").textContent = "loading formats...";
+  try {
+    const info = await api("GET", "/formats?link=" + encodeURIComponent(link));
+    const select = $("format");
+    select.replaceChildren(el("option", { value: "", textContent: "default" }));
+    for (const f of info.formats) {
+      const quality = f.qualityLabel || f.quality;
+      const size = f.contentLength ? " " + bytes(f.contentLength) : "";
+      const audio = f.audioChannels ? "" : " (no audio)";
+      select.append(el("option", {
+        value: String(f.itag),
+        textContent: `${f.itag} ${f.mimeType} ${quality}${size}${audio}`,
+      }));
+    }
+    $("message").textContent = info.title;
+  } catch (err) {
+    $("message").textContent = err.message;
+  }
+}
+
+async function submit(event) {
+  event.preventDefault();
+  try {
+    const job = await api("POST", "/jobs", {
+      link: $("link").value,
+      format: $("format").value,
+      dst: $("dst").value,
+      audioOnly: $("audio-only").checked,
+      videoOnly: $("video-only").checked,
+      author: $("author").checked,
+    });
+    $("message").textContent = "queued job " + job.id;
+    refreshJobs();
+  } catch (err) {
+    $("message").textContent = err.message;
+  }
+}
+
+async function refreshJobs() {
+  const jobs = await api("GET", "/jobs");
+  const list = $("jobs");
+  list.replaceChildren();
+  for (const job of jobs.reverse()) {
+    const node = el("div", { className: "job" },
+      el("div", {}, `#${job.id} [${job.status}] ${job.request.link}`));
+    for (const item of job.items || []) {
+      node.append(el("div", { className: "item" },
+        bar(item),
+        el("span", { className: item.error ? "error" : "" }, item.title + (item.error ? ": " + item.error : ""))));
+    }
+    for (const err of job.errors || []) {
+      node.append(el("div", { className: "error" }, err));
+    }
+    if (job.status === "queued" || job.status === "running") {
+      node.append(el("button", {
+        textContent: "cancel",
+        onclick: () => api("DELETE", "/jobs/" + job.id).then(refreshJobs),
+      }));
+    }
+    list.append(node);
+  }
+}
+
+async function refreshFiles() {
+  const files = await api("GET", "/files");
+  const list = $("files");
+  list.replaceChildren();
+  for (const file of files) {
+    const path = "/files/" + file.path.split("/").map(encodeURIComponent).join("/");
+    list.append(el("li", {},
+      el("a", { href: "api" + path, download: "" }, file.path),
+      ` ${bytes(file.size)} `,
+      el("button", {
+        textContent: "delete",
+        onclick: () => api("DELETE", path).then(refreshFiles),
+      })));
+  }
+}
+
+$("load-formats").addEventListener("click", loadFormats);
+$("submit").addEventListener("submit", submit);
+$("refresh-files").addEventListener("click", refreshFiles);
+
+refreshJobs();
+refreshFiles();
+setInterval(refreshJobs, 1000);
@@ -0,0 +1,82 @@
+package webui
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/downloader"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/extractor"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/models"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/server"
+)
+
+// static holds the browser UI, it's embedded so the binary works offline.
+//
+//go:embed static
+var static embed.FS
+
+// FormatsResponse is the body of GET /api/formats.
+type FormatsResponse struct {
+	VideoID string              `json:"videoId"`
+	Title   string              `json:"title"`
+	Author  string              `json:"author"`
+	Formats []models.FormatInfo `json:"formats"`
+}
+
+// Handler serves the UI on / and the server REST API on /api/,
+// plus GET /api/formats?link= listing the formats available for a video.
+func Handler(srv *server.Server) http.Handler {
+	assets, _ := fs.Sub(static, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServerFS(assets))
+	mux.HandleFunc("GET /api/formats", formatsHandler(srv))
+	mux.Handle("/api/", http.StripPrefix("/api", srv.Handler()))
+	return mux
+}
+
+// formatsHandler lists formats with the client of srv, the one its downloads use.
+func formatsHandler(srv *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleFormats(w, r, srv.HTTPClient)
+	}
+}
+
+func handleFormats(w http.ResponseWriter, r *http.Request, client *http.Client) {
+	link := r.URL.Query().Get("link")
+	if link == "" {
+		writeError(w, http.StatusBadRequest, errors.New("link is required"))
+		return
+	}
+
+	media, err := downloader.GetMedia(r.Context(), link, downloader.Options{
+		Registry:   extractor.NewDefaultRegistry(client),
+		HTTPClient: client,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	resp := FormatsResponse{
+		VideoID: media.ID,
+		Title:   media.Title,
+		Author:  media.Author,
+		Formats: media.Formats,
+	}
+	if resp.Formats == nil {
+		resp.Formats = []models.FormatInfo{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
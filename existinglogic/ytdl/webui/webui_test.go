@@ -0,0 +1,82 @@
+package webui
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/server"
+	"github.com/mtdem/ytdownloader/existinglogic/ytdl/testserver"
+)
+
+func newTestUI(t *testing.T) (*httptest.Server, *testserver.Server) {
+	t.Helper()
+	youtube := testserver.New()
+	t.Cleanup(youtube.Close)
+
+	srv := server.New(t.TempDir(), 1)
+	srv.HTTPClient = youtube.Client()
+	t.Cleanup(srv.Close)
+
+	ui := httptest.NewServer(Handler(srv))
+	t.Cleanup(ui.Close)
+	return ui, youtube
+}
+
+func get(t *testing.T, url string, want int) []byte {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != want {
+		t.Fatalf("GET %s: status %d, want %d: %s", url, resp.StatusCode, want, body)
+	}
+	return body
+}
+
+func TestHandler(t *testing.T) {
+	ui, _ := newTestUI(t)
+
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		if body := get(t, ui.URL+path, http.StatusOK); len(body) == 0 {
+			t.Errorf("%s is empty", path)
+		}
+	}
+	if body := get(t, ui.URL+"/", http.StatusOK); !strings.Contains(string(body), "<html") {
+		t.Errorf("/ isn't the page: %.100s", body)
+	}
+	get(t, ui.URL+"/missing.js", http.StatusNotFound)
+
+	// the REST API is under /api
+	var jobs []server.Job
+	if err := json.Unmarshal(get(t, ui.URL+"/api/jobs", http.StatusOK), &jobs); err != nil || len(jobs) != 0 {
+		t.Errorf("jobs = %v, %v", jobs, err)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	ui, youtube := newTestUI(t)
+	youtube.AddVideo(testserver.Video{ID: "aaaaaaaaaaa", Title: "First Video", Author: "Ann"})
+
+	// the video only exists behind the client of the server
+	var resp FormatsResponse
+	body := get(t, ui.URL+"/api/formats?link=https://youtu.be/aaaaaaaaaaa", http.StatusOK)
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.VideoID != "aaaaaaaaaaa" || resp.Title != "First Video" || resp.Author != "Ann" || len(resp.Formats) == 0 {
+		t.Errorf("formats = %+v", resp)
+	}
+
+	get(t, ui.URL+"/api/formats", http.StatusBadRequest)
+	get(t, ui.URL+"/api/formats?link=https://youtu.be/bbbbbbbbbbb", http.StatusBadGateway)
+}